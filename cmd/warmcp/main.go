@@ -1,29 +1,132 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"warmcp/pkg/agenda"
 	"warmcp/pkg/common"
+	"warmcp/pkg/doctor"
+	"warmcp/pkg/focus"
+	"warmcp/pkg/prompts"
 	"warmcp/pkg/taskwarrior"
 	"warmcp/pkg/timewarrior"
 
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
+// loadConfig reads the config file and puts it into effect. A --timezone flag
+// takes precedence over the file.
+func loadConfig(path, timezone string) (*common.Config, error) {
+	cfg, err := common.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if timezone != "" {
+		cfg.Timezone = timezone
+	}
+	return cfg, common.SetConfig(cfg)
+}
+
+// reloadOnHangup re-reads the config file on SIGHUP. An invalid file is
+// reported and the previous configuration stays in effect.
+func reloadOnHangup(s *server.MCPServer, path, timezone string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		old := common.CurrentConfig()
+		cfg, err := loadConfig(path, timezone)
+		if err != nil {
+			log.Printf("reloading config: %v", err)
+			continue
+		}
+		for _, section := range common.RestartRequired(old, cfg) {
+			log.Printf("reloading config: %s changed; restart warmcp to apply it", section)
+		}
+		if old.Binaries != cfg.Binaries {
+			common.ProbeCapabilities()
+			common.GateTools(s)
+		}
+		// The policy or the binaries may have changed which tools are listed.
+		s.SendNotificationToAllClients(mcp.MethodNotificationToolsListChanged, nil)
+		log.Printf("reloaded config from %s", path)
+	}
+}
+
 func main() {
-	s := server.NewMCPServer(
-		"warmcp",
-		"1.0.0",
+	configPath := flag.String("config", common.DefaultConfigPath(), "path to the warmcp config file")
+	timezone := flag.String("timezone", "", "IANA timezone for dates in tool output (default: the config file, TZ or the system zone)")
+	flag.Parse()
+	cfg, err := loadConfig(*configPath, *timezone)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Fatal: %v\n", err)
+		os.Exit(1)
+	}
+	caps := common.ProbeCapabilities()
+	for _, info := range []common.BinaryInfo{caps.Task, caps.Timew} {
+		if info.Error != "" {
+			log.Printf("probing %s: %s", info.Binary, info.Error)
+		}
+	}
+
+	var s *server.MCPServer
+	s = server.NewMCPServer(
+		cfg.Server.Name,
+		cfg.Server.Version,
 		server.WithToolCapabilities(true),
 		server.WithPromptCapabilities(true),
 		server.WithResourceCapabilities(true, false),
+		server.WithLogging(),
+		server.WithHooks(common.SessionHooks()),
+		server.WithToolFilter(common.PolicyFilter),
+		server.WithToolHandlerMiddleware(common.PolicyMiddleware(func(name string) *server.ServerTool { return s.GetTool(name) })),
+		server.WithToolHandlerMiddleware(common.TimezoneMiddleware),
+		server.WithToolHandlerMiddleware(common.ProfileMiddleware),
 	)
 
 	taskwarrior.RegisterHandlers(s)
-	timewarrior.RegisterHandlers(s)
+	if cfg.Features.Timewarrior {
+		timewarrior.RegisterHandlers(s)
+	}
+	if cfg.Features.Agenda {
+		agenda.RegisterHandlers(s)
+	}
+	if cfg.Features.Focus {
+		focus.RegisterHandlers(s)
+	}
+	doctor.RegisterHandlers(s)
+	prompts.RegisterHandlers(s)
 	common.RegisterMCPFeatures(s)
+	common.AddProfileArguments(s)
+	common.GateTools(s)
 
-	fmt.Println("warmcp server starting on stdio...")
-	if err := server.ServeStdio(s); err != nil {
-		fmt.Printf("Fatal: %v\n", err)
+	if cfg.Features.UDAWatch {
+		go taskwarrior.WatchUDAs(s, 5*time.Second)
+	}
+	if cfg.Features.Reminders {
+		go taskwarrior.WatchReminders(s)
+	}
+	go reloadOnHangup(s, *configPath, *timezone)
+
+	switch cfg.Transport.Type {
+	case "sse":
+		log.Printf("warmcp server starting on %s (SSE)...", cfg.Transport.Address)
+		err = server.NewSSEServer(s).Start(cfg.Transport.Address)
+	case "http":
+		log.Printf("warmcp server starting on %s (streamable HTTP)...", cfg.Transport.Address)
+		err = server.NewStreamableHTTPServer(s).Start(cfg.Transport.Address)
+	default:
+		// Stdout carries the protocol, so only log to stderr.
+		log.Printf("warmcp server starting on stdio...")
+		err = server.ServeStdio(s)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Fatal: %v\n", err)
+		os.Exit(1)
 	}
 }
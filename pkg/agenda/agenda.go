@@ -0,0 +1,247 @@
+package agenda
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"warmcp/pkg/common"
+	"warmcp/pkg/taskwarrior"
+	"warmcp/pkg/timewarrior"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// now is the clock used for the default range; tests replace it.
+var now = time.Now
+
+// AgendaTask is a task as it appears on an agenda day.
+type AgendaTask struct {
+	UUID        string   `json:"uuid"`
+	ID          int      `json:"id,omitempty"`
+	Description string   `json:"description"`
+	Project     string   `json:"project,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Status      string   `json:"status"`
+	Time        string   `json:"time"`
+}
+
+// TrackedInterval is the part of a timew interval that falls on an agenda day.
+type TrackedInterval struct {
+	Start   string   `json:"start"`
+	End     string   `json:"end"`
+	Open    bool     `json:"open,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	Minutes int      `json:"minutes"`
+}
+
+// Day is one agenda day: what was planned and what was actually tracked.
+type Day struct {
+	Date           string            `json:"date"`
+	Due            []AgendaTask      `json:"due"`
+	Scheduled      []AgendaTask      `json:"scheduled"`
+	WaitingUntil   []AgendaTask      `json:"waiting_until"`
+	Tracked        []TrackedInterval `json:"tracked"`
+	PlannedTasks   int               `json:"planned_tasks"`
+	CompletedTasks int               `json:"completed_tasks"`
+	TrackedMinutes int               `json:"tracked_minutes"`
+}
+
+func RegisterHandlers(s *server.MCPServer) {
+	s.AddTool(mcp.NewTool("warmcp_agenda",
+		mcp.WithDescription("Per-day agenda of tasks due, scheduled or waiting until each day, next to the time tracked that day. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		common.WithTimezone(),
+		mcp.WithString("start", mcp.Description("First day (YYYY-MM-DD). Default: today")),
+		mcp.WithString("end", mcp.Description("Last day (YYYY-MM-DD). Default: six days after start")),
+		mcp.WithString("filter", mcp.Description("Additional Taskwarrior filter")),
+		mcp.WithString("format", mcp.Enum("json", "markdown"), mcp.Description("Output format. Default: json")),
+	), agendaHandler)
+}
+
+func agendaTask(t taskwarrior.Task, at time.Time) AgendaTask {
+	return AgendaTask{
+		UUID:        t.UUID,
+		ID:          t.ID,
+		Description: t.Description,
+		Project:     t.Project,
+		Tags:        t.Tags,
+		Status:      t.Status,
+		Time:        at.Format("15:04"),
+	}
+}
+
+func onDay(value string, from, to time.Time) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	t, err := common.ParseDate(value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	t = t.In(from.Location())
+	return t, !t.Before(from) && t.Before(to)
+}
+
+// Build lays out tasks and intervals over the days from start to end inclusive.
+func Build(tasks []taskwarrior.Task, intervals []timewarrior.Interval, start, end time.Time) []Day {
+	var days []Day
+	for from := start; !from.After(end); from = from.AddDate(0, 0, 1) {
+		to := from.AddDate(0, 0, 1)
+		d := Day{
+			Date:         from.Format(common.DayLayout),
+			Due:          []AgendaTask{},
+			Scheduled:    []AgendaTask{},
+			WaitingUntil: []AgendaTask{},
+			Tracked:      []TrackedInterval{},
+		}
+
+		planned := map[string]bool{}
+		for _, t := range tasks {
+			if at, ok := onDay(t.Due, from, to); ok {
+				d.Due = append(d.Due, agendaTask(t, at))
+				planned[t.UUID] = true
+			}
+			if at, ok := onDay(t.Scheduled, from, to); ok {
+				d.Scheduled = append(d.Scheduled, agendaTask(t, at))
+				planned[t.UUID] = true
+			}
+			if at, ok := onDay(t.Wait, from, to); ok {
+				d.WaitingUntil = append(d.WaitingUntil, agendaTask(t, at))
+			}
+		}
+		d.PlannedTasks = len(planned)
+		for _, t := range tasks {
+			if _, ok := onDay(t.End, from, to); ok && t.Status == "completed" {
+				d.CompletedTasks++
+			}
+		}
+
+		for _, iv := range intervals {
+			s, e, err := iv.Times()
+			if err != nil {
+				continue
+			}
+			if s.Before(from) {
+				s = from
+			}
+			if e.After(to) {
+				e = to
+			}
+			if !e.After(s) {
+				continue
+			}
+			tracked := TrackedInterval{
+				Start:   s.In(from.Location()).Format("15:04"),
+				End:     e.In(from.Location()).Format("15:04"),
+				Open:    iv.Open(),
+				Tags:    iv.Tags,
+				Minutes: int(e.Sub(s).Minutes()),
+			}
+			d.Tracked = append(d.Tracked, tracked)
+			d.TrackedMinutes += tracked.Minutes
+		}
+		for _, list := range [][]AgendaTask{d.Due, d.Scheduled, d.WaitingUntil} {
+			sort.SliceStable(list, func(i, j int) bool { return list[i].Time < list[j].Time })
+		}
+		days = append(days, d)
+	}
+	return days
+}
+
+func formatMinutes(m int) string {
+	return fmt.Sprintf("%dh%02dm", m/60, m%60)
+}
+
+func markdownTasks(b *strings.Builder, title string, tasks []AgendaTask) {
+	if len(tasks) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n**%s**\n", title)
+	for _, t := range tasks {
+		box := " "
+		if t.Status == "completed" {
+			box = "x"
+		}
+		fmt.Fprintf(b, "- [%s] %s %s", box, t.Time, t.Description)
+		if t.Project != "" {
+			fmt.Fprintf(b, " (%s)", t.Project)
+		}
+		b.WriteString("\n")
+	}
+}
+
+// Markdown renders the agenda with one section per day.
+func Markdown(days []Day) string {
+	var b strings.Builder
+	for i, d := range days {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		date, _ := time.Parse(common.DayLayout, d.Date)
+		fmt.Fprintf(&b, "## %s %s\n", date.Format("Mon"), d.Date)
+		fmt.Fprintf(&b, "Planned: %d tasks, %d completed. Tracked: %s\n",
+			d.PlannedTasks, d.CompletedTasks, formatMinutes(d.TrackedMinutes))
+		markdownTasks(&b, "Due", d.Due)
+		markdownTasks(&b, "Scheduled", d.Scheduled)
+		markdownTasks(&b, "Waiting until", d.WaitingUntil)
+		if len(d.Tracked) > 0 {
+			b.WriteString("\n**Tracked**\n")
+			for _, iv := range d.Tracked {
+				end := iv.End
+				if iv.Open {
+					end += " (running)"
+				}
+				fmt.Fprintf(&b, "- %s-%s %s (%s)\n", iv.Start, end, strings.Join(iv.Tags, ", "), formatMinutes(iv.Minutes))
+			}
+		}
+	}
+	return b.String()
+}
+
+func agendaHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	startArg, _ := argsMap["start"].(string)
+	endArg, _ := argsMap["end"].(string)
+	filter, _ := argsMap["filter"].(string)
+	format, _ := argsMap["format"].(string)
+
+	loc := common.Location(ctx)
+	start := common.StartOfDay(now(), loc)
+	if startArg != "" {
+		t, err := common.ParseDay(startArg, loc)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		start = t
+	}
+	end := start.AddDate(0, 0, 6)
+	if endArg != "" {
+		t, err := common.ParseDay(endArg, loc)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		end = t
+	}
+	if end.Before(start) {
+		return mcp.NewToolResultError("end must not be before start"), nil
+	}
+
+	filters := append([]string{"status.not:deleted", "status.not:recurring"}, strings.Fields(filter)...)
+	tasks, err := taskwarrior.ExportTasks(ctx, filters...)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	intervals, err := timewarrior.ExportBetween(ctx, start, end.AddDate(0, 0, 1))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	days := Build(tasks, intervals, start, end)
+	if format == "markdown" {
+		return mcp.NewToolResultText(Markdown(days)), nil
+	}
+	return common.JSONResult(days)
+}
@@ -0,0 +1,40 @@
+package agenda
+
+import (
+	"testing"
+	"time"
+	"warmcp/pkg/taskwarrior"
+	"warmcp/pkg/timewarrior"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildAgenda(t *testing.T) {
+	tasks := []taskwarrior.Task{
+		{UUID: "a", Description: "Report", Status: "completed", Due: "20240108T160000Z", End: "20240108T150000Z"},
+		{UUID: "b", Description: "Review", Status: "pending", Scheduled: "20240109T090000Z"},
+		{UUID: "c", Description: "Follow up", Status: "waiting", Wait: "20240109T080000Z"},
+	}
+	intervals := []timewarrior.Interval{
+		{Start: "20240108T230000Z", End: "20240109T010000Z", Tags: []string{"Report"}},
+	}
+	start := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	days := Build(tasks, intervals, start, start.AddDate(0, 0, 1))
+	assert.Len(t, days, 2)
+
+	assert.Equal(t, "2024-01-08", days[0].Date)
+	assert.Equal(t, "16:00", days[0].Due[0].Time)
+	assert.Equal(t, 1, days[0].PlannedTasks)
+	assert.Equal(t, 1, days[0].CompletedTasks)
+	assert.Equal(t, 60, days[0].TrackedMinutes)
+
+	assert.Equal(t, "b", days[1].Scheduled[0].UUID)
+	assert.Equal(t, "c", days[1].WaitingUntil[0].UUID)
+	assert.Equal(t, TrackedInterval{Start: "00:00", End: "01:00", Tags: []string{"Report"}, Minutes: 60}, days[1].Tracked[0])
+
+	md := Markdown(days)
+	assert.Contains(t, md, "## Mon 2024-01-08")
+	assert.Contains(t, md, "- [x] 16:00 Report")
+	assert.Contains(t, md, "Tracked: 1h00m")
+}
@@ -0,0 +1,192 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Version is a major.minor.patch release number.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseVersion finds the first version number in s, such as the output of
+// `task --version` ("3.1.0") or `timew --version` ("1.4.3").
+func ParseVersion(s string) (Version, error) {
+	m := versionPattern.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("no version number in %q", s)
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	return v, nil
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether v is min or a later release.
+func (v Version) AtLeast(min Version) bool {
+	if v.Major != min.Major {
+		return v.Major > min.Major
+	}
+	if v.Minor != min.Minor {
+		return v.Minor > min.Minor
+	}
+	return v.Patch >= min.Patch
+}
+
+// BinaryInfo is what probing found out about the task or timew binary.
+type BinaryInfo struct {
+	Binary  string `json:"binary"`
+	Path    string `json:"path,omitempty"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+
+	version Version
+	known   bool
+}
+
+// DisabledTool is a tool left out because the installed binary is too old.
+type DisabledTool struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Capabilities is the warmcp://capabilities resource.
+type Capabilities struct {
+	Task          BinaryInfo     `json:"task"`
+	Timew         BinaryInfo     `json:"timew"`
+	DisabledTools []DisabledTool `json:"disabled_tools"`
+}
+
+// requirement is the minimum release of a binary a tool needs.
+type requirement struct {
+	binary string
+	min    Version
+}
+
+var capabilities = struct {
+	sync.RWMutex
+	caps         Capabilities
+	requirements map[string]requirement
+	// gated holds the tools GateTools removed, so a later call can restore them.
+	gated map[string]server.ServerTool
+}{requirements: map[string]requirement{}, gated: map[string]server.ServerTool{}}
+
+// RequireVersion records that a tool needs at least the given release of
+// binary ("task" or "timew"). GateTools removes it when an older one is installed.
+func RequireVersion(tool, binary, min string) {
+	v, err := ParseVersion(min)
+	if err != nil {
+		panic(err)
+	}
+	capabilities.Lock()
+	defer capabilities.Unlock()
+	capabilities.requirements[tool] = requirement{binary: binary, min: v}
+}
+
+// ProbeBinary runs `<binary> --version` and resolves where the binary lives.
+// Unlike ProbeCapabilities it does not change what the server reports.
+func ProbeBinary(ctx context.Context, binary string) BinaryInfo {
+	info := BinaryInfo{Binary: binary}
+	if path, err := exec.LookPath(binary); err == nil {
+		info.Path = path
+	}
+	out, err := RunCommand(ctx, binary, nil, nil, "--version")
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	v, err := ParseVersion(out)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	info.Version, info.version, info.known = v.String(), v, true
+	return info
+}
+
+// ProbeCapabilities detects the versions of the configured task and timew
+// binaries. A binary that is missing or fails is reported, not fatal.
+func ProbeCapabilities() Capabilities {
+	ctx, bins := context.Background(), CurrentConfig().Binaries
+	task, timew := ProbeBinary(ctx, bins.Task), ProbeBinary(ctx, bins.Timew)
+	capabilities.Lock()
+	defer capabilities.Unlock()
+	capabilities.caps.Task, capabilities.caps.Timew = task, timew
+	return capabilities.caps
+}
+
+// CurrentCapabilities returns the result of the last probe.
+func CurrentCapabilities() Capabilities {
+	capabilities.RLock()
+	defer capabilities.RUnlock()
+	return capabilities.caps
+}
+
+// GateTools removes the registered tools whose version requirement the
+// probed binaries do not meet, and restores the ones it removed before that
+// the binaries now support. Tools are kept when a version is unknown. Call it
+// after probing and after all tools are registered, and again after every
+// probe that follows.
+func GateTools(s *server.MCPServer) {
+	capabilities.Lock()
+	defer capabilities.Unlock()
+	disabled := []DisabledTool{}
+	for tool, req := range capabilities.requirements {
+		info := capabilities.caps.Task
+		if req.binary == "timew" {
+			info = capabilities.caps.Timew
+		}
+		gated, wasGated := capabilities.gated[tool]
+		if !info.known || info.version.AtLeast(req.min) {
+			if wasGated {
+				s.AddTools(gated)
+				delete(capabilities.gated, tool)
+				log.Printf("enabling %s: found %s %s", tool, req.binary, info.Version)
+			}
+			continue
+		}
+		reason := fmt.Sprintf("needs %s %s or later, found %s", req.binary, req.min, info.Version)
+		if t := s.GetTool(tool); t != nil {
+			capabilities.gated[tool] = *t
+			s.DeleteTools(tool)
+			log.Printf("disabling %s: %s", tool, reason)
+		} else if !wasGated {
+			continue
+		}
+		disabled = append(disabled, DisabledTool{Name: tool, Reason: reason})
+	}
+	sort.Slice(disabled, func(i, j int) bool { return disabled[i].Name < disabled[j].Name })
+	capabilities.caps.DisabledTools = disabled
+}
+
+func capabilitiesResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	data, err := json.MarshalIndent(CurrentCapabilities(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      req.Params.URI,
+			MIMEType: "application/json",
+			Text:     string(data),
+		},
+	}, nil
+}
@@ -1,15 +1,26 @@
 package common
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
-// GetTaskrcPath returns the path to the taskrc file, respecting TASKRC env var and XDG_CONFIG_HOME.
-func GetTaskrcPath() string {
+// GetTaskrcPath returns the path to the taskrc file, respecting the profile
+// of ctx, TASKRC env var and XDG_CONFIG_HOME.
+func GetTaskrcPath(ctx context.Context) string {
+	return taskrcPath(CurrentProfile(ctx))
+}
+
+func taskrcPath(p Profile) string {
+	if p.Taskrc != "" {
+		return p.Taskrc
+	}
 	if val := os.Getenv("TASKRC"); val != "" {
 		return val
 	}
@@ -21,8 +32,16 @@ func GetTaskrcPath() string {
 	return filepath.Join(xdg, "task", "taskrc")
 }
 
-// GetTimewConfigPath returns the path to the timewarrior config file, respecting TIMEW_CONFIG env var and XDG_CONFIG_HOME.
-func GetTimewConfigPath() string {
+// GetTimewConfigPath returns the path to the timewarrior config file,
+// respecting the database of the profile of ctx, TIMEW_CONFIG env var and XDG_CONFIG_HOME.
+func GetTimewConfigPath(ctx context.Context) string {
+	return timewConfigPath(CurrentProfile(ctx))
+}
+
+func timewConfigPath(p Profile) string {
+	if p.TimewarriorDB != "" {
+		return filepath.Join(p.TimewarriorDB, "timewarrior.cfg")
+	}
 	if val := os.Getenv("TIMEW_CONFIG"); val != "" {
 		return val
 	}
@@ -36,18 +55,34 @@ func GetTimewConfigPath() string {
 
 // CommandRunner defines the interface for executing commands.
 type CommandRunner interface {
-	Run(name string, env []string, baseArgs []string, args ...string) (string, error)
+	Run(ctx context.Context, name string, env []string, baseArgs []string, args ...string) (string, error)
 }
 
 // DefaultRunner is the standard implementation using os/exec.
 type DefaultRunner struct{}
 
-func (r DefaultRunner) Run(name string, env []string, baseArgs []string, args ...string) (string, error) {
+// Run executes the command, killing it when ctx is done or after the
+// configured command timeout.
+func (r DefaultRunner) Run(ctx context.Context, name string, env []string, baseArgs []string, args ...string) (string, error) {
 	finalArgs := append(baseArgs, args...)
-	cmd := exec.Command(name, finalArgs...)
+	cmdCtx := ctx
+	timeout := time.Duration(CurrentConfig().Timeouts.Command)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		cmdCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(cmdCtx, name, finalArgs...)
 	cmd.Env = append(os.Environ(), env...)
 	out, err := cmd.CombinedOutput()
 	output := strings.TrimSpace(string(out))
+	switch {
+	case ctx.Err() != nil:
+		// The request was cancelled or the client went away.
+		err = ctx.Err()
+	case errors.Is(cmdCtx.Err(), context.DeadlineExceeded):
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
 	return output, err
 }
 
@@ -55,8 +90,8 @@ func (r DefaultRunner) Run(name string, env []string, baseArgs []string, args ..
 var Runner CommandRunner = DefaultRunner{}
 
 // RunCommand executes a command using the global Runner and wraps errors with output.
-func RunCommand(name string, env []string, baseArgs []string, args ...string) (string, error) {
-	out, err := Runner.Run(name, env, baseArgs, args...)
+func RunCommand(ctx context.Context, name string, env []string, baseArgs []string, args ...string) (string, error) {
+	out, err := Runner.Run(ctx, name, env, baseArgs, args...)
 	if err != nil {
 		return "", fmt.Errorf("%s error: %v\nOutput: %s", name, err, out)
 	}
@@ -0,0 +1,374 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the warmcp config file, by default $XDG_CONFIG_HOME/warmcp/config.yaml:
+//
+//	server: {name: warmcp, version: 1.0.0}
+//	timezone: Europe/Berlin
+//	transport: {type: http, address: "localhost:8080"}
+//	binaries: {task: /usr/local/bin/task, timew: timew}
+//	task:
+//	  overrides: [rc.hooks=off]
+//	  default_filter: status:pending -someday
+//	timeouts: {command: 30s}
+//	policy:
+//	  read_only: false
+//	  deny_tools: [task_raw, timew_raw]
+//	features: {timewarrior: true, agenda: true, focus: true, reminders: true, uda_watch: true}
+//	reminders: {due: 1h, scheduled: 15m, interval: 1m}
+//	billing:
+//	  currency: EUR
+//	  rounding: up
+//	  increment: 15
+//	  rates: [{tag: acme, client: Acme Corp, rate: 120}]
+//	default_profile: personal
+//	profiles:
+//	  work: {taskrc: ~/.config/task/work.rc, taskdata: ~/work/task, timewarriordb: ~/work/timew}
+//
+// Everything except server, transport and features can be reloaded with SIGHUP.
+type Config struct {
+	Path           string             `yaml:"-"`
+	Server         ServerConfig       `yaml:"server"`
+	Timezone       string             `yaml:"timezone"`
+	Transport      Transport          `yaml:"transport"`
+	Binaries       Binaries           `yaml:"binaries"`
+	Task           TaskConfig         `yaml:"task"`
+	Timeouts       Timeouts           `yaml:"timeouts"`
+	Policy         Policy             `yaml:"policy"`
+	Features       Features           `yaml:"features"`
+	Reminders      Reminders          `yaml:"reminders"`
+	Billing        Billing            `yaml:"billing"`
+	DefaultProfile string             `yaml:"default_profile"`
+	Profiles       map[string]Profile `yaml:"profiles"`
+}
+
+// ServerConfig is the name and version the server reports to clients.
+type ServerConfig struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+}
+
+// Transport selects how clients connect: stdio, sse or http (streamable HTTP).
+type Transport struct {
+	Type    string `yaml:"type"`
+	Address string `yaml:"address"`
+}
+
+// Binaries are the task and timew executables, as names on PATH or paths.
+type Binaries struct {
+	Task  string `yaml:"task"`
+	Timew string `yaml:"timew"`
+}
+
+// TaskConfig holds extra rc overrides passed to every task command and the
+// filter used when a listing tool gets none.
+type TaskConfig struct {
+	Overrides     []string `yaml:"overrides"`
+	DefaultFilter string   `yaml:"default_filter"`
+}
+
+// Timeouts limit how long a task or timew command may run. Zero means no limit.
+type Timeouts struct {
+	Command Duration `yaml:"command"`
+}
+
+// Features turn groups of tools and background work on or off.
+type Features struct {
+	Timewarrior bool `yaml:"timewarrior"`
+	Agenda      bool `yaml:"agenda"`
+	Focus       bool `yaml:"focus"`
+	Reminders   bool `yaml:"reminders"`
+	UDAWatch    bool `yaml:"uda_watch"`
+}
+
+// Reminders sets how long before a due or scheduled date a task reminder
+// fires and how often the reminder scheduler checks. features.reminders turns
+// the scheduler on.
+type Reminders struct {
+	Due       Duration `yaml:"due"`
+	Scheduled Duration `yaml:"scheduled"`
+	Interval  Duration `yaml:"interval"`
+}
+
+// Billing is the rate table of timew_timesheet. Rounding is up, down or
+// nearest and applies to each invoice line; Increment is in minutes, 0
+// billing exact time.
+type Billing struct {
+	Currency  string        `yaml:"currency"`
+	Rounding  string        `yaml:"rounding"`
+	Increment int           `yaml:"increment"`
+	Rates     []BillingRate `yaml:"rates"`
+}
+
+// BillingRate bills time tagged Tag to Client, by default the tag itself, at
+// Rate per hour.
+type BillingRate struct {
+	Tag    string  `yaml:"tag"`
+	Client string  `yaml:"client"`
+	Rate   float64 `yaml:"rate"`
+}
+
+// Duration is a time.Duration written as "30s" or "2m" in the config file.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil || parsed < 0 {
+		return fmt.Errorf("line %d: invalid duration %q: use a value such as 30s or 2m", value.Line, value.Value)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// DefaultConfig is the configuration used when there is no config file.
+func DefaultConfig() *Config {
+	return &Config{
+		Server:    ServerConfig{Name: "warmcp", Version: "1.0.0"},
+		Transport: Transport{Type: "stdio"},
+		Binaries:  Binaries{Task: "task", Timew: "timew"},
+		Task:      TaskConfig{DefaultFilter: "status:pending"},
+		Features:  Features{Timewarrior: true, Agenda: true, Focus: true, Reminders: true, UDAWatch: true},
+		Reminders: Reminders{Due: Duration(time.Hour), Interval: Duration(time.Minute)},
+	}
+}
+
+// DefaultConfigPath returns the path to the warmcp config file, respecting
+// WARMCP_CONFIG env var and XDG_CONFIG_HOME.
+func DefaultConfigPath() string {
+	if val := os.Getenv("WARMCP_CONFIG"); val != "" {
+		return val
+	}
+	home, _ := os.UserHomeDir()
+	xdg := os.Getenv("XDG_CONFIG_HOME")
+	if xdg == "" {
+		xdg = filepath.Join(home, ".config")
+	}
+	return filepath.Join(xdg, "warmcp", "config.yaml")
+}
+
+// GetConfigPath returns the path of the config file in use.
+func GetConfigPath() string {
+	if p := CurrentConfig().Path; p != "" {
+		return p
+	}
+	return DefaultConfigPath()
+}
+
+// expandPath resolves a leading ~ and environment variables in a config path.
+func expandPath(p string) string {
+	p = os.ExpandEnv(p)
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		home, _ := os.UserHomeDir()
+		p = filepath.Join(home, p[1:])
+	}
+	return p
+}
+
+// LoadConfig reads and validates the config file at path. Settings it leaves
+// out keep their defaults, and a missing file is the default configuration.
+func LoadConfig(path string) (*Config, error) {
+	c := DefaultConfig()
+	c.Path = path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if problems := c.validate(); len(problems) > 0 {
+		return nil, fmt.Errorf("%s:\n  %s", path, strings.Join(problems, "\n  "))
+	}
+	return c, nil
+}
+
+// validate normalizes the config and lists everything wrong with it.
+func (c *Config) validate() []string {
+	var problems []string
+	if c.Server.Name == "" {
+		problems = append(problems, "server.name must not be empty")
+	}
+	if _, err := LoadLocation(c.Timezone); err != nil {
+		problems = append(problems, "timezone: "+err.Error())
+	}
+	switch c.Transport.Type {
+	case "stdio":
+	case "sse", "http":
+		if c.Transport.Address == "" {
+			problems = append(problems, fmt.Sprintf("transport.address is required for the %s transport", c.Transport.Type))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("transport.type %q must be stdio, sse or http", c.Transport.Type))
+	}
+	if c.Binaries.Task == "" || c.Binaries.Timew == "" {
+		problems = append(problems, "binaries.task and binaries.timew must not be empty")
+	}
+	c.Binaries.Task, c.Binaries.Timew = expandPath(c.Binaries.Task), expandPath(c.Binaries.Timew)
+	for _, o := range c.Task.Overrides {
+		if !strings.HasPrefix(o, "rc.") || !strings.Contains(o, "=") {
+			problems = append(problems, fmt.Sprintf("task.overrides: %q is not of the form rc.<name>=<value>", o))
+		}
+	}
+	if c.Reminders.Interval < Duration(time.Second) {
+		problems = append(problems, "reminders.interval must be at least 1s")
+	}
+	switch c.Billing.Rounding {
+	case "", "up", "down", "nearest":
+	default:
+		problems = append(problems, fmt.Sprintf("billing.rounding %q must be up, down or nearest", c.Billing.Rounding))
+	}
+	if c.Billing.Increment < 0 {
+		problems = append(problems, "billing.increment must not be negative")
+	}
+	billed := map[string]bool{}
+	for i, r := range c.Billing.Rates {
+		switch {
+		case r.Tag == "":
+			problems = append(problems, fmt.Sprintf("billing.rates[%d] has no tag", i))
+		case billed[r.Tag]:
+			problems = append(problems, fmt.Sprintf("billing.rates: tag %s has more than one rate", r.Tag))
+		case r.Rate < 0:
+			problems = append(problems, fmt.Sprintf("billing.rates: the rate of %s must not be negative", r.Tag))
+		}
+		billed[r.Tag] = true
+		if r.Client == "" {
+			c.Billing.Rates[i].Client = r.Tag
+		}
+	}
+	for _, name := range c.Policy.Deny {
+		if slices.Contains(c.Policy.Allow, name) {
+			problems = append(problems, fmt.Sprintf("policy: %s is in both allow_tools and deny_tools", name))
+		}
+	}
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := c.Profiles[name]
+		if p.Taskrc == "" && p.Taskdata == "" && p.TimewarriorDB == "" {
+			problems = append(problems, fmt.Sprintf("profiles.%s sets none of taskrc, taskdata or timewarriordb", name))
+		}
+		p.Name = name
+		p.Taskrc, p.Taskdata, p.TimewarriorDB = expandPath(p.Taskrc), expandPath(p.Taskdata), expandPath(p.TimewarriorDB)
+		c.Profiles[name] = p
+	}
+	if _, ok := c.Profiles[c.DefaultProfile]; c.DefaultProfile != "" && !ok {
+		problems = append(problems, fmt.Sprintf("default_profile %q is not defined under profiles", c.DefaultProfile))
+	}
+	return problems
+}
+
+// RestartRequired lists the settings that differ between two configs but
+// only take effect when the server starts.
+func RestartRequired(old, c *Config) []string {
+	var changed []string
+	if old.Server != c.Server {
+		changed = append(changed, "server")
+	}
+	if old.Transport != c.Transport {
+		changed = append(changed, "transport")
+	}
+	if old.Features != c.Features {
+		changed = append(changed, "features")
+	}
+	return changed
+}
+
+var config = struct {
+	sync.RWMutex
+	c *Config
+}{c: DefaultConfig()}
+
+// CurrentConfig returns the configuration in effect. Callers must not modify it.
+func CurrentConfig() *Config {
+	config.RLock()
+	defer config.RUnlock()
+	return config.c
+}
+
+// SetConfig puts c into effect, including its profiles and timezone.
+func SetConfig(c *Config) error {
+	if err := SetDefaultLocation(c.Timezone); err != nil {
+		return err
+	}
+	SetProfiles(c.Profiles, c.DefaultProfile)
+	config.Lock()
+	config.c = c
+	config.Unlock()
+	return nil
+}
+
+// Policy restricts which tools clients may see and call. read_only hides
+// every tool that is not annotated as read-only.
+type Policy struct {
+	ReadOnly bool     `yaml:"read_only"`
+	Allow    []string `yaml:"allow_tools"`
+	Deny     []string `yaml:"deny_tools"`
+}
+
+// Check reports why the policy refuses a tool, or nil if it is allowed.
+func (p Policy) Check(tool mcp.Tool) error {
+	switch {
+	case len(p.Allow) > 0 && !slices.Contains(p.Allow, tool.Name):
+		return fmt.Errorf("tool %s is not in policy.allow_tools", tool.Name)
+	case slices.Contains(p.Deny, tool.Name):
+		return fmt.Errorf("tool %s is denied by policy.deny_tools", tool.Name)
+	case p.ReadOnly && (tool.Annotations.ReadOnlyHint == nil || !*tool.Annotations.ReadOnlyHint):
+		return fmt.Errorf("tool %s changes data and policy.read_only is set", tool.Name)
+	}
+	return nil
+}
+
+// PolicyFilter hides the tools the current policy refuses from tool listings.
+func PolicyFilter(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	policy := CurrentConfig().Policy
+	allowed := make([]mcp.Tool, 0, len(tools))
+	for _, t := range tools {
+		if policy.Check(t) == nil {
+			allowed = append(allowed, t)
+		}
+	}
+	return allowed
+}
+
+// PolicyMiddleware refuses calls to tools the current policy does not allow.
+// lookup finds a registered tool by name.
+func PolicyMiddleware(lookup func(name string) *server.ServerTool) server.ToolHandlerMiddleware {
+	return func(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			tool := mcp.Tool{Name: req.Params.Name}
+			if t := lookup(req.Params.Name); t != nil {
+				tool = t.Tool
+			}
+			if err := CurrentConfig().Policy.Check(tool); err != nil {
+				return mcp.NewToolResultError(err.Error()), nil
+			}
+			return next(ctx, req)
+		}
+	}
+}
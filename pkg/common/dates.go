@@ -0,0 +1,164 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DateLayout is the ISO 8601 basic UTC format used in Taskwarrior and
+// Timewarrior exports.
+const DateLayout = ICalDateLayout
+
+// LocalLayout is the extended form without a zone, as accepted on the task
+// and timew command lines and printed by `task calc`.
+const LocalLayout = "2006-01-02T15:04:05"
+
+// DayLayout is the calendar day form used by tool arguments.
+const DayLayout = "2006-01-02"
+
+// parseLayouts are tried in order by ParseDate.
+var parseLayouts = []string{DateLayout, time.RFC3339}
+
+// ParseDate parses a timestamp from a Taskwarrior or Timewarrior export. It
+// also accepts RFC 3339 and extended UTC forms.
+func ParseDate(s string) (time.Time, error) {
+	for _, layout := range parseLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+var defaultLocation = struct {
+	sync.RWMutex
+	loc *time.Location
+}{loc: time.Local}
+
+// SetDefaultLocation sets the timezone outputs are rendered in when a request
+// does not name one. By default that is the process zone, which honours TZ.
+func SetDefaultLocation(name string) error {
+	loc, err := LoadLocation(name)
+	if err != nil {
+		return err
+	}
+	defaultLocation.Lock()
+	defaultLocation.loc = loc
+	defaultLocation.Unlock()
+	return nil
+}
+
+// DefaultLocation returns the configured output timezone.
+func DefaultLocation() *time.Location {
+	defaultLocation.RLock()
+	defer defaultLocation.RUnlock()
+	return defaultLocation.loc
+}
+
+// LoadLocation resolves an IANA timezone name. An empty name is the default location.
+func LoadLocation(name string) (*time.Location, error) {
+	switch name {
+	case "":
+		return DefaultLocation(), nil
+	case "local", "Local":
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q: use an IANA name such as Europe/Berlin", name)
+	}
+	return loc, nil
+}
+
+type locationKey struct{}
+
+// WithLocation attaches the output timezone of a request to ctx.
+func WithLocation(ctx context.Context, loc *time.Location) context.Context {
+	return context.WithValue(ctx, locationKey{}, loc)
+}
+
+// Location returns the output timezone of a request, falling back to the default.
+func Location(ctx context.Context) *time.Location {
+	if loc, ok := ctx.Value(locationKey{}).(*time.Location); ok {
+		return loc
+	}
+	return DefaultLocation()
+}
+
+// WithTimezone declares the optional per-request `timezone` argument.
+func WithTimezone() mcp.ToolOption {
+	return mcp.WithString("timezone", mcp.Description("IANA timezone for dates and day boundaries, e.g. Europe/Berlin. Default: the server's timezone"))
+}
+
+// TimezoneMiddleware resolves the `timezone` argument of any tool call into
+// the request context, rejecting unknown zones before the handler runs.
+func TimezoneMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		argsMap, _ := req.Params.Arguments.(map[string]any)
+		name, _ := argsMap["timezone"].(string)
+		loc, err := LoadLocation(name)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return next(WithLocation(ctx, loc), req)
+	}
+}
+
+// StartOfDay returns midnight of the day t falls on in loc.
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// ParseDay parses a YYYY-MM-DD argument as midnight in loc.
+func ParseDay(s string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation(DayLayout, s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: expected YYYY-MM-DD", s)
+	}
+	return t, nil
+}
+
+// LocalizeJSON rewrites every string in a JSON document that is an export
+// timestamp as RFC 3339 in loc. Dates in nested objects, such as annotation
+// entries and date UDAs, are converted too.
+func LocalizeJSON(data []byte, loc *time.Location) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(localize(v, loc))
+}
+
+func localize(v any, loc *time.Location) any {
+	switch val := v.(type) {
+	case string:
+		if t, err := time.Parse(DateLayout, val); err == nil {
+			return t.In(loc).Format(time.RFC3339)
+		}
+	case []any:
+		for i := range val {
+			val[i] = localize(val[i], loc)
+		}
+	case map[string]any:
+		for k := range val {
+			val[k] = localize(val[k], loc)
+		}
+	}
+	return v
+}
+
+// CommandDate formats t for the task and timew command lines, which read
+// dates without a zone in the server process's own timezone.
+func CommandDate(t time.Time) string {
+	return t.In(time.Local).Format(LocalLayout)
+}
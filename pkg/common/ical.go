@@ -0,0 +1,213 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ICalDateLayout is the RFC 5545 UTC DATE-TIME form, which is also the
+// basic format used by Taskwarrior and Timewarrior exports.
+const ICalDateLayout = "20060102T150405Z"
+
+// ICalProperty is a single content line such as "DUE:20240101T120000Z".
+// Params holds any ";KEY=VALUE" parameters, without the leading semicolon.
+type ICalProperty struct {
+	Name   string
+	Params string
+	Value  string
+}
+
+// ICalComponent is a VTODO, VEVENT or similar block.
+type ICalComponent struct {
+	Name       string
+	Properties []ICalProperty
+}
+
+// Add appends a property unless value is empty.
+func (c *ICalComponent) Add(name, value string) {
+	if value != "" {
+		c.Properties = append(c.Properties, ICalProperty{Name: name, Value: value})
+	}
+}
+
+// AddText appends a TEXT property, escaping it as RFC 5545 requires.
+func (c *ICalComponent) AddText(name, value string) {
+	c.Add(name, ICalEscape(value))
+}
+
+// ICalEscape escapes backslashes, semicolons, commas and newlines in TEXT values.
+func ICalEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// ICalUnescape reverses ICalEscape.
+func ICalUnescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// foldLine splits content lines longer than 75 octets, continuing with a space.
+func foldLine(line string) string {
+	if len(line) <= 75 {
+		return line
+	}
+	var b strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		// Never split a multi-byte UTF-8 sequence.
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		limit = 74
+	}
+	b.WriteString(line)
+	return b.String()
+}
+
+// WriteCalendar wraps components in a VCALENDAR object with CRLF line endings.
+func WriteCalendar(components []ICalComponent) string {
+	var b strings.Builder
+	write := func(line string) {
+		b.WriteString(foldLine(line))
+		b.WriteString("\r\n")
+	}
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:-//warmcp//warmcp//EN")
+	write("CALSCALE:GREGORIAN")
+	stamp := time.Now().UTC().Format(ICalDateLayout)
+	for _, c := range components {
+		write("BEGIN:" + c.Name)
+		hasStamp := false
+		for _, p := range c.Properties {
+			hasStamp = hasStamp || p.Name == "DTSTAMP"
+		}
+		if !hasStamp {
+			write("DTSTAMP:" + stamp)
+		}
+		for _, p := range c.Properties {
+			name := p.Name
+			if p.Params != "" {
+				name += ";" + p.Params
+			}
+			write(name + ":" + p.Value)
+		}
+		write("END:" + c.Name)
+	}
+	write("END:VCALENDAR")
+	return b.String()
+}
+
+// unfoldLines joins continuation lines and splits the text into content lines.
+func unfoldLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// parseContentLine splits "NAME;PARAMS:VALUE", honouring quoted parameter values.
+func parseContentLine(line string) (ICalProperty, bool) {
+	inQuotes := false
+	for i, r := range line {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ':' && !inQuotes:
+			name, params, _ := strings.Cut(line[:i], ";")
+			return ICalProperty{Name: strings.ToUpper(name), Params: params, Value: line[i+1:]}, true
+		}
+	}
+	return ICalProperty{}, false
+}
+
+// ParseCalendar reads the components of an iCalendar stream. Nested
+// components (such as VALARM inside VTODO) are returned separately, and the
+// VCALENDAR wrapper itself is omitted.
+func ParseCalendar(text string) ([]ICalComponent, error) {
+	var components []ICalComponent
+	var stack []*ICalComponent
+	for n, line := range unfoldLines(text) {
+		p, ok := parseContentLine(line)
+		if !ok {
+			return nil, fmt.Errorf("line %d: malformed content line %q", n+1, line)
+		}
+		switch p.Name {
+		case "BEGIN":
+			stack = append(stack, &ICalComponent{Name: strings.ToUpper(p.Value)})
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(p.Value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", n+1, p.Value)
+			}
+			c := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if c.Name != "VCALENDAR" {
+				components = append(components, *c)
+			}
+		default:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Properties = append(stack[len(stack)-1].Properties, p)
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unterminated %s component", stack[len(stack)-1].Name)
+	}
+	return components, nil
+}
+
+// Get returns the first property with the given name.
+func (c ICalComponent) Get(name string) (ICalProperty, bool) {
+	for _, p := range c.Properties {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return ICalProperty{}, false
+}
+
+// ParseICalTime parses DATE-TIME (UTC or floating) and DATE values. Floating
+// times and dates are interpreted in loc.
+func ParseICalTime(value string, loc *time.Location) (time.Time, error) {
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse(ICalDateLayout, value)
+	case len(value) == len("20060102"):
+		return time.ParseInLocation("20060102", value, loc)
+	}
+	return time.ParseInLocation("20060102T150405", value, loc)
+}
+
+// Param returns the value of a property parameter such as TZID.
+func (p ICalProperty) Param(name string) string {
+	for _, param := range strings.Split(p.Params, ";") {
+		key, val, ok := strings.Cut(param, "=")
+		if ok && strings.EqualFold(key, name) {
+			return strings.Trim(val, `"`)
+		}
+	}
+	return ""
+}
+
+// Time parses the property as a date or date-time, honouring its TZID
+// parameter. Floating values without a TZID are read in loc.
+func (p ICalProperty) Time(loc *time.Location) (time.Time, error) {
+	if tzid := p.Param("TZID"); tzid != "" {
+		if tz, err := time.LoadLocation(tzid); err == nil {
+			loc = tz
+		}
+	}
+	return ParseICalTime(p.Value, loc)
+}
@@ -2,8 +2,10 @@ package common
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"sync/atomic"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -15,9 +17,11 @@ func RegisterMCPFeatures(s *server.MCPServer) {
 		mcp.WithPromptDescription("Prepares a summary of pending tasks and time spent for review."),
 	), dailyPlannerPromptHandler)
 
-	s.AddPrompt(mcp.NewPrompt("setup_check",
-		mcp.WithPromptDescription("Check if Taskwarrior and Timewarrior are correctly installed and configured."),
-	), setupCheckPromptHandler)
+	// --- TOOLS ---
+	s.AddTool(mcp.NewTool("warmcp_profiles",
+		mcp.WithDescription("List the named profiles (separate Taskwarrior and Timewarrior databases) from the warmcp config file, with the default profile and the paths each one uses. Pass a profile name as the `profile` argument of any tool to use it. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), profilesHandler)
 
 	// --- RESOURCES ---
 	s.AddResource(mcp.Resource{
@@ -55,6 +59,13 @@ func RegisterMCPFeatures(s *server.MCPServer) {
 		MIMEType:    "text/plain",
 	}, taskUDAsResourceHandler)
 
+	s.AddResource(mcp.Resource{
+		URI:         "warmcp://capabilities",
+		Name:        "warmcp Capabilities",
+		Description: "Detected task and timew versions, and the tools disabled because a binary is too old",
+		MIMEType:    "application/json",
+	}, capabilitiesResourceHandler)
+
 	s.AddResource(mcp.Resource{
 		URI:         "task://diagnostics",
 		Name:        "Taskwarrior Diagnostics",
@@ -78,22 +89,8 @@ func dailyPlannerPromptHandler(ctx context.Context, req mcp.GetPromptRequest) (*
 	}, nil
 }
 
-func setupCheckPromptHandler(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
-	return &mcp.GetPromptResult{
-		Description: "Check environment",
-		Messages: []mcp.PromptMessage{
-			{
-				Role: mcp.RoleUser,
-				Content: mcp.NewTextContent(
-					"Please run `task_raw command:--version` and `timew_raw command:--version` to verify the installation.",
-				),
-			},
-		},
-	}, nil
-}
-
 func taskConfigResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-	path := GetTaskrcPath()
+	path := GetTaskrcPath(ctx)
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("could not read taskrc at %s: %v", path, err)
@@ -109,7 +106,7 @@ func taskConfigResourceHandler(ctx context.Context, req mcp.ReadResourceRequest)
 
 func taskSummaryResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
 	// Simple summary via CLI
-	out, err := RunCommand("task", nil, []string{"rc.verbose=nothing", "rc.confirmation=off"}, "summary")
+	out, err := RunCommand(ctx, CurrentConfig().Binaries.Task, TaskEnv(ctx), []string{"rc.verbose=nothing", "rc.confirmation=off"}, "summary")
 	if err != nil {
 		return nil, err
 	}
@@ -123,7 +120,7 @@ func taskSummaryResourceHandler(ctx context.Context, req mcp.ReadResourceRequest
 }
 
 func taskTagsResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-	out, err := RunCommand("task", nil, []string{"rc.verbose=nothing", "rc.confirmation=off"}, "tags")
+	out, err := RunCommand(ctx, CurrentConfig().Binaries.Task, TaskEnv(ctx), []string{"rc.verbose=nothing", "rc.confirmation=off"}, "tags")
 	if err != nil {
 		return nil, err
 	}
@@ -137,7 +134,7 @@ func taskTagsResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) (
 }
 
 func taskProjectsResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-	out, err := RunCommand("task", nil, []string{"rc.verbose=nothing", "rc.confirmation=off"}, "projects")
+	out, err := RunCommand(ctx, CurrentConfig().Binaries.Task, TaskEnv(ctx), []string{"rc.verbose=nothing", "rc.confirmation=off"}, "projects")
 	if err != nil {
 		return nil, err
 	}
@@ -151,7 +148,7 @@ func taskProjectsResourceHandler(ctx context.Context, req mcp.ReadResourceReques
 }
 
 func taskUDAsResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-	out, err := RunCommand("task", nil, []string{"rc.verbose=nothing", "rc.confirmation=off"}, "udas")
+	out, err := RunCommand(ctx, CurrentConfig().Binaries.Task, TaskEnv(ctx), []string{"rc.verbose=nothing", "rc.confirmation=off"}, "udas")
 	if err != nil {
 		return nil, err
 	}
@@ -165,7 +162,7 @@ func taskUDAsResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) (
 }
 
 func taskDiagnosticsResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
-	out, err := RunCommand("task", nil, []string{"rc.verbose=nothing", "rc.confirmation=off"}, "diagnostics")
+	out, err := RunCommand(ctx, CurrentConfig().Binaries.Task, TaskEnv(ctx), []string{"rc.verbose=nothing", "rc.confirmation=off"}, "diagnostics")
 	if err != nil {
 		return nil, err
 	}
@@ -177,3 +174,42 @@ func taskDiagnosticsResourceHandler(ctx context.Context, req mcp.ReadResourceReq
 		},
 	}, nil
 }
+
+// sessions counts the connected clients.
+var sessions atomic.Int64
+
+// SessionHooks keeps count of the connected clients for HasSessions.
+func SessionHooks() *server.Hooks {
+	hooks := &server.Hooks{}
+	hooks.AddOnRegisterSession(func(ctx context.Context, session server.ClientSession) { sessions.Add(1) })
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) { sessions.Add(-1) })
+	return hooks
+}
+
+// HasSessions reports whether any client is connected, so background work
+// that only produces notifications can pause while nobody would receive them.
+func HasSessions() bool {
+	return sessions.Load() > 0
+}
+
+// JSONResult returns v as the JSON text of a tool result.
+func JSONResult(v any) (*mcp.CallToolResult, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
+
+// LocalJSONResult is JSONResult with the export dates in v given in the
+// timezone of the request.
+func LocalJSONResult(ctx context.Context, v any) (*mcp.CallToolResult, error) {
+	data, err := json.Marshal(v)
+	if err == nil {
+		data, err = LocalizeJSON(data, Location(ctx))
+	}
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
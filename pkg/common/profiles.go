@@ -0,0 +1,185 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Profile is a named set of Taskwarrior and Timewarrior locations, so one
+// server can manage several databases. Empty fields keep the environment's value.
+type Profile struct {
+	Name          string `yaml:"-" json:"name"`
+	Taskrc        string `yaml:"taskrc" json:"taskrc,omitempty"`
+	Taskdata      string `yaml:"taskdata" json:"taskdata,omitempty"`
+	TimewarriorDB string `yaml:"timewarriordb" json:"timewarriordb,omitempty"`
+}
+
+var profiles = struct {
+	sync.RWMutex
+	byName map[string]Profile
+	def    string
+}{}
+
+// SetProfiles replaces the configured profiles.
+func SetProfiles(byName map[string]Profile, def string) {
+	profiles.Lock()
+	defer profiles.Unlock()
+	profiles.byName = byName
+	profiles.def = def
+}
+
+// Profiles returns the configured profiles sorted by name, and the default profile's name.
+func Profiles() ([]Profile, string) {
+	profiles.RLock()
+	defer profiles.RUnlock()
+	list := make([]Profile, 0, len(profiles.byName))
+	for _, p := range profiles.byName {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, profiles.def
+}
+
+// LookupProfile resolves a profile name. An empty name is the default
+// profile, or the environment's paths when no default is configured.
+func LookupProfile(name string) (Profile, error) {
+	profiles.RLock()
+	defer profiles.RUnlock()
+	if name == "" {
+		name = profiles.def
+		if name == "" {
+			return Profile{}, nil
+		}
+	}
+	p, ok := profiles.byName[name]
+	if !ok {
+		names := make([]string, 0, len(profiles.byName))
+		for n := range profiles.byName {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return Profile{}, fmt.Errorf("unknown profile %q: no profiles are configured in %s", name, GetConfigPath())
+		}
+		return Profile{}, fmt.Errorf("unknown profile %q: available profiles are %s", name, strings.Join(names, ", "))
+	}
+	return p, nil
+}
+
+type profileKey struct{}
+
+// WithProfile attaches the profile a request runs in to ctx.
+func WithProfile(ctx context.Context, p Profile) context.Context {
+	return context.WithValue(ctx, profileKey{}, p)
+}
+
+// CurrentProfile returns the profile of a request. Work started without one,
+// such as a background loop, runs in the default profile.
+func CurrentProfile(ctx context.Context) Profile {
+	if p, ok := ctx.Value(profileKey{}).(Profile); ok {
+		return p
+	}
+	p, _ := LookupProfile("")
+	return p
+}
+
+// TaskEnv returns the environment for task commands in the profile of ctx.
+func TaskEnv(ctx context.Context) []string {
+	p := CurrentProfile(ctx)
+	env := []string{fmt.Sprintf("TASKRC=%s", taskrcPath(p))}
+	if p.Taskdata != "" {
+		env = append(env, fmt.Sprintf("TASKDATA=%s", p.Taskdata))
+	}
+	return env
+}
+
+// TimewEnv returns the environment for timew commands in the profile of ctx.
+func TimewEnv(ctx context.Context) []string {
+	p := CurrentProfile(ctx)
+	env := []string{fmt.Sprintf("TIMEW_CONFIG=%s", timewConfigPath(p))}
+	if p.TimewarriorDB != "" {
+		env = append(env, fmt.Sprintf("TIMEWARRIORDB=%s", p.TimewarriorDB))
+	}
+	return env
+}
+
+// ProfileMiddleware resolves the `profile` argument of any tool call into the
+// request context, rejecting unknown names before the handler runs.
+func ProfileMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		argsMap, _ := req.Params.Arguments.(map[string]any)
+		name, _ := argsMap["profile"].(string)
+		p, err := LookupProfile(name)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return next(WithProfile(ctx, p), req)
+	}
+}
+
+// ProfileTool adds the optional `profile` argument to a tool's schema.
+func ProfileTool(tool mcp.Tool) mcp.Tool {
+	if tool.RawInputSchema != nil {
+		return tool
+	}
+	if _, ok := tool.InputSchema.Properties["profile"]; ok {
+		return tool
+	}
+	props := make(map[string]any, len(tool.InputSchema.Properties)+1)
+	for k, v := range tool.InputSchema.Properties {
+		props[k] = v
+	}
+	props["profile"] = map[string]any{
+		"type":        "string",
+		"description": "Named profile (database) to use, as listed by warmcp_profiles. Default: the default profile",
+	}
+	tool.InputSchema.Properties = props
+	return tool
+}
+
+// AddProfileArguments adds the `profile` argument to every registered tool.
+// Call it after all tools are registered.
+func AddProfileArguments(s *server.MCPServer) {
+	var tools []server.ServerTool
+	for _, t := range s.ListTools() {
+		tools = append(tools, server.ServerTool{Tool: ProfileTool(t.Tool), Handler: t.Handler})
+	}
+	s.AddTools(tools...)
+}
+
+// profileInfo is one warmcp_profiles entry with the paths commands will use.
+type profileInfo struct {
+	Profile
+	Default     bool   `json:"default"`
+	TaskrcPath  string `json:"effective_taskrc"`
+	TimewConfig string `json:"effective_timew_config"`
+}
+
+func profilesHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	list, def := Profiles()
+	infos := []profileInfo{}
+	for _, p := range list {
+		infos = append(infos, profileInfo{
+			Profile:     p,
+			Default:     p.Name == def,
+			TaskrcPath:  taskrcPath(p),
+			TimewConfig: timewConfigPath(p),
+		})
+	}
+	data, err := json.MarshalIndent(map[string]any{
+		"config":   GetConfigPath(),
+		"default":  def,
+		"profiles": infos,
+	}, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,425 @@
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"warmcp/pkg/common"
+	"warmcp/pkg/taskwarrior"
+	"warmcp/pkg/timewarrior"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Check statuses, from best to worst.
+const (
+	Pass = "pass"
+	Warn = "warn"
+	Fail = "fail"
+)
+
+var severity = map[string]int{Pass: 0, Warn: 1, Fail: 2}
+
+// Check is one line of the checklist. Fix says what to do when it did not pass.
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	Fix    string `json:"fix,omitempty"`
+}
+
+// Report is the warmcp_doctor result. Status is the worst status of any check.
+type Report struct {
+	Status  string  `json:"status"`
+	Profile string  `json:"profile,omitempty"`
+	Checks  []Check `json:"checks"`
+}
+
+func (r *Report) add(c Check) {
+	r.Checks = append(r.Checks, c)
+	if severity[c.Status] > severity[r.Status] {
+		r.Status = c.Status
+	}
+}
+
+// Run checks the environment of the profile of ctx.
+func Run(ctx context.Context) Report {
+	r := Report{Status: Pass, Profile: common.CurrentProfile(ctx).Name}
+	bins := common.CurrentConfig().Binaries
+	task := common.ProbeBinary(ctx, bins.Task)
+	r.add(checkBinary("task binary", "binaries.task", task))
+	r.add(checkBinary("timew binary", "binaries.timew", common.ProbeBinary(ctx, bins.Timew)))
+	if disabled := common.CurrentCapabilities().DisabledTools; len(disabled) > 0 {
+		var lines []string
+		for _, d := range disabled {
+			lines = append(lines, d.Name+" "+d.Reason)
+		}
+		r.add(Check{Name: "tool versions", Status: Warn, Detail: "disabled: " + strings.Join(lines, "; "),
+			Fix: "upgrade the binaries, then restart warmcp"})
+	}
+
+	taskCfg, check := checkTaskrc(ctx)
+	r.add(check)
+	r.add(checkTimewConfig(ctx))
+	var dataDir string
+	if taskCfg != nil {
+		dataDir = taskDataDir(ctx, taskCfg)
+		r.add(checkWritable("task data directory", dataDir, "set data.location in taskrc, or taskdata in the warmcp profile"))
+		r.add(checkHooks(taskCfg, dataDir))
+		r.add(checkSync(ctx, taskCfg, task))
+		r.add(checkDates(ctx, taskCfg))
+	}
+	r.add(checkWritable("timew database", timewDataDir(ctx), "set TIMEWARRIORDB, or timewarriordb in the warmcp profile"))
+	r.add(checkEnv(ctx, dataDir))
+	return r
+}
+
+func checkBinary(name, key string, info common.BinaryInfo) Check {
+	c := Check{Name: name}
+	switch {
+	case info.Path == "":
+		c.Status, c.Detail = Fail, fmt.Sprintf("%s was not found on PATH", info.Binary)
+		c.Fix = fmt.Sprintf("install it, or set %s in %s to its full path", key, common.GetConfigPath())
+	case info.Error != "":
+		c.Status, c.Detail = Fail, fmt.Sprintf("%s --version failed: %s", info.Path, info.Error)
+		c.Fix = "run it by hand to see what is wrong"
+	default:
+		c.Status, c.Detail = Pass, fmt.Sprintf("%s %s", info.Path, info.Version)
+	}
+	return c
+}
+
+// checkTaskrc returns the effective Taskwarrior configuration if task could read it.
+func checkTaskrc(ctx context.Context) (map[string]string, Check) {
+	path := common.GetTaskrcPath(ctx)
+	c := Check{Name: "taskrc"}
+	if _, err := os.Stat(path); err != nil {
+		c.Status, c.Detail = Fail, fmt.Sprintf("%s does not exist", path)
+		c.Fix = fmt.Sprintf("create it with `touch %s`, or point TASKRC or the profile's taskrc at your taskrc", path)
+		return nil, c
+	}
+	cfg, err := taskwarrior.ShowConfig(ctx)
+	if err != nil {
+		c.Status, c.Detail = Fail, fmt.Sprintf("task could not read %s: %v", path, err)
+		c.Fix = "fix the line task reports, or run `task show` to see the error"
+		return nil, c
+	}
+	c.Status, c.Detail = Pass, fmt.Sprintf("%s (%d settings)", path, len(cfg))
+	return cfg, c
+}
+
+// checkTimewConfig looks for lines Timewarrior would not understand.
+func checkTimewConfig(ctx context.Context) Check {
+	path := common.GetTimewConfigPath(ctx)
+	c := Check{Name: "timew config"}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		c.Status, c.Detail = Warn, fmt.Sprintf("%s does not exist, so Timewarrior defaults apply", path)
+		c.Fix = "run `timew` once to create it"
+		return c
+	}
+	if err != nil {
+		c.Status, c.Detail, c.Fix = Fail, err.Error(), "make the file readable"
+		return c
+	}
+	var bad []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "import ") ||
+			strings.Contains(line, "=") || strings.HasSuffix(line, ":") {
+			continue
+		}
+		bad = append(bad, fmt.Sprintf("line %d: %q", i+1, line))
+	}
+	if len(bad) > 0 {
+		c.Status, c.Detail = Fail, fmt.Sprintf("%s has lines that are not settings: %s", path, strings.Join(bad, ", "))
+		c.Fix = "use key = value, or a block opened with name:"
+		return c
+	}
+	c.Status, c.Detail = Pass, path
+	return c
+}
+
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, p[1:])
+	}
+	return p
+}
+
+// taskDataDir follows Taskwarrior's lookup: TASKDATA, then data.location.
+func taskDataDir(ctx context.Context, cfg map[string]string) string {
+	if p := common.CurrentProfile(ctx); p.Taskdata != "" {
+		return p.Taskdata
+	}
+	if val := os.Getenv("TASKDATA"); val != "" {
+		return val
+	}
+	return expandHome(cfg["data.location"])
+}
+
+// timewDataDir follows Timewarrior's lookup: TIMEWARRIORDB, then ~/.timewarrior,
+// then the XDG data directory.
+func timewDataDir(ctx context.Context) string {
+	if p := common.CurrentProfile(ctx); p.TimewarriorDB != "" {
+		return p.TimewarriorDB
+	}
+	if val := os.Getenv("TIMEWARRIORDB"); val != "" {
+		return val
+	}
+	home, _ := os.UserHomeDir()
+	if info, err := os.Stat(filepath.Join(home, ".timewarrior")); err == nil && info.IsDir() {
+		return filepath.Join(home, ".timewarrior")
+	}
+	xdg := os.Getenv("XDG_DATA_HOME")
+	if xdg == "" {
+		xdg = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(xdg, "timewarrior")
+}
+
+func checkWritable(name, dir, fix string) Check {
+	c := Check{Name: name}
+	if dir == "" {
+		c.Status, c.Detail, c.Fix = Warn, "no directory is configured", fix
+		return c
+	}
+	info, err := os.Stat(dir)
+	if err != nil || !info.IsDir() {
+		c.Status, c.Detail = Fail, fmt.Sprintf("%s is not a directory", dir)
+		c.Fix = fmt.Sprintf("create it with `mkdir -p %s`, or %s", dir, fix)
+		return c
+	}
+	f, err := os.CreateTemp(dir, ".warmcp-doctor-*")
+	if err != nil {
+		c.Status, c.Detail = Fail, fmt.Sprintf("%s is not writable: %v", dir, err)
+		c.Fix = fmt.Sprintf("`chmod u+w %s`, or check who owns it", dir)
+		return c
+	}
+	f.Close()
+	os.Remove(f.Name())
+	c.Status, c.Detail = Pass, dir
+	return c
+}
+
+// checkHooks lists the installed hooks and looks for the Timewarrior on-modify
+// hook, which tracks time when tasks are started and stopped.
+func checkHooks(cfg map[string]string, dataDir string) Check {
+	c := Check{Name: "hooks"}
+	dir := expandHome(cfg["hooks.location"])
+	if dir == "" {
+		dir = filepath.Join(dataDir, "hooks")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		c.Status, c.Detail = Warn, fmt.Sprintf("no hooks directory at %s", dir)
+		c.Fix = "install the Timewarrior hook: `mkdir -p " + dir + " && cp /usr/share/doc/timew/ext/on-modify.timewarrior " + dir + "/ && chmod +x " + dir + "/on-modify.timewarrior`"
+		return c
+	}
+	var hooks, notExec []string
+	timewHook := false
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), "on-") {
+			continue
+		}
+		hooks = append(hooks, e.Name())
+		info, err := e.Info()
+		if err != nil || info.Mode()&0o111 == 0 {
+			notExec = append(notExec, e.Name())
+			continue
+		}
+		if strings.HasPrefix(e.Name(), "on-modify") && strings.Contains(e.Name(), "timew") {
+			timewHook = true
+		}
+	}
+	sort.Strings(hooks)
+	switch {
+	case len(notExec) > 0:
+		c.Status, c.Detail = Fail, fmt.Sprintf("hooks in %s are not executable, so task skips them: %s", dir, strings.Join(notExec, ", "))
+		c.Fix = fmt.Sprintf("`chmod +x` them in %s", dir)
+	case !timewHook:
+		c.Status, c.Detail = Warn, fmt.Sprintf("no Timewarrior on-modify hook in %s (found: %s)", dir, strings.Join(hooks, ", "))
+		c.Fix = "`cp /usr/share/doc/timew/ext/on-modify.timewarrior " + dir + "/ && chmod +x " + dir + "/on-modify.timewarrior`, so starting a task tracks time"
+	default:
+		c.Status, c.Detail = Pass, fmt.Sprintf("%s: %s", dir, strings.Join(hooks, ", "))
+	}
+	return c
+}
+
+// missingKeys returns the keys that have no value in cfg.
+func missingKeys(cfg map[string]string, keys ...string) []string {
+	var missing []string
+	for _, k := range keys {
+		if cfg[k] == "" {
+			missing = append(missing, k)
+		}
+	}
+	return missing
+}
+
+// checkSync verifies that whichever sync backend is configured is complete,
+// and that it is one the installed Taskwarrior supports.
+func checkSync(ctx context.Context, cfg map[string]string, task common.BinaryInfo) Check {
+	c := Check{Name: "sync"}
+	v, _ := common.ParseVersion(task.Version)
+	taskwarrior3 := task.Version != "" && v.Major >= 3
+	var missing []string
+	switch {
+	case cfg["sync.server.url"] != "" || cfg["sync.server.origin"] != "" || cfg["sync.server.client_id"] != "":
+		c.Detail = "sync server " + cfg["sync.server.url"] + cfg["sync.server.origin"]
+		missing = missingKeys(cfg, "sync.server.client_id", "sync.encryption_secret")
+		if cfg["sync.server.url"] == "" && cfg["sync.server.origin"] == "" {
+			missing = append(missing, "sync.server.url")
+		}
+	case cfg["sync.local.server_dir"] != "":
+		c.Detail = "local sync to " + cfg["sync.local.server_dir"]
+		if _, err := os.Stat(expandHome(cfg["sync.local.server_dir"])); err != nil {
+			c.Status = Warn
+			c.Detail += ", which does not exist yet"
+			c.Fix = "it is created on the first `task sync`"
+			return c
+		}
+	case cfg["sync.gcp.bucket"] != "" || cfg["sync.aws.bucket"] != "":
+		c.Detail = "cloud sync to bucket " + cfg["sync.gcp.bucket"] + cfg["sync.aws.bucket"]
+		missing = missingKeys(cfg, "sync.encryption_secret")
+	case cfg["taskd.server"] != "":
+		c.Detail = "taskd server " + cfg["taskd.server"]
+		if taskwarrior3 {
+			c.Status, c.Fix = Fail, "Taskwarrior 3 dropped taskd: move to sync.server.* and run `task sync init`"
+			c.Detail += ", which Taskwarrior 3 no longer supports"
+			return c
+		}
+		missing = missingKeys(cfg, "taskd.credentials", "taskd.certificate", "taskd.key", "taskd.ca")
+	default:
+		c.Status, c.Detail = Pass, "sync is not configured"
+		return c
+	}
+	if len(missing) > 0 {
+		c.Status = Fail
+		c.Detail += "; missing " + strings.Join(missing, ", ")
+		c.Fix = "set the missing keys in " + common.GetTaskrcPath(ctx)
+		return c
+	}
+	c.Status = Pass
+	return c
+}
+
+// checkDates makes sure task reads dates the way warmcp writes them on the
+// command line, whatever dateformat the taskrc sets.
+func checkDates(ctx context.Context, cfg map[string]string) Check {
+	c := Check{Name: "date format"}
+	probe := common.CommandDate(time.Date(2024, 1, 15, 10, 30, 0, 0, time.Local))
+	out, err := (&taskwarrior.TaskCommand{Command: "calc", Modifications: []string{probe}}).Run(ctx)
+	out = strings.TrimSpace(out)
+	if err != nil || out != probe {
+		c.Status = Fail
+		c.Detail = fmt.Sprintf("task calc %s gave %q", probe, out)
+		if err != nil {
+			c.Detail = fmt.Sprintf("task calc %s failed: %v", probe, err)
+		}
+		c.Fix = "check dateformat (" + cfg["dateformat"] + ") and date.iso in the taskrc; warmcp passes ISO dates"
+		return c
+	}
+	c.Status, c.Detail = Pass, "task reads ISO dates"
+	if f := cfg["dateformat"]; f != "" {
+		c.Detail += ", dateformat is " + f
+	}
+	return c
+}
+
+// diagnosticsPaths reads the "Name: /path (found), ..." lines of a
+// `task diagnostics` or `timew diagnostics` report.
+func diagnosticsPaths(out string) map[string]string {
+	paths := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		k, v, ok := strings.Cut(strings.TrimSpace(line), ": ")
+		if !ok {
+			continue
+		}
+		v, _, _ = strings.Cut(strings.TrimSpace(v), " (")
+		paths[k] = v
+	}
+	return paths
+}
+
+// samePath compares paths as reported by task and timew with the ones warmcp
+// computed. Timewarrior may report its database as the data subdirectory.
+func samePath(got, want string, alsoData bool) bool {
+	got, want = filepath.Clean(expandHome(got)), filepath.Clean(want)
+	return got == want || alsoData && got == filepath.Join(want, "data")
+}
+
+// checkEnv asks task and timew, through their diagnostics reports, which
+// configuration and data they actually resolved, and compares that with the
+// paths warmcp means to use. A wrapper script or shell profile that resets
+// the variables shows up here.
+func checkEnv(ctx context.Context, taskData string) Check {
+	c := Check{Name: "process environment"}
+	taskOut, err := (&taskwarrior.TaskCommand{Command: "diagnostics"}).Run(ctx)
+	if err != nil {
+		c.Status, c.Detail = Warn, fmt.Sprintf("could not run task diagnostics: %v", err)
+		return c
+	}
+	timewOut, err := timewarrior.Diagnostics(ctx)
+	if err != nil {
+		c.Status, c.Detail = Warn, fmt.Sprintf("could not run timew diagnostics: %v", err)
+		return c
+	}
+	taskPaths, timewPaths := diagnosticsPaths(taskOut), diagnosticsPaths(timewOut)
+	checks := []struct {
+		label, got, want string
+		alsoData         bool
+	}{
+		{"task config", taskPaths["File"], common.GetTaskrcPath(ctx), false},
+		{"task data", taskPaths["Data"], taskData, false},
+		{"timew config", timewPaths["Cfg"], common.GetTimewConfigPath(ctx), false},
+		{"timew database", timewPaths["Database"], timewDataDir(ctx), true},
+	}
+	var wrong, unknown, seen []string
+	for _, p := range checks {
+		switch {
+		case p.want == "":
+			continue
+		case p.got == "":
+			unknown = append(unknown, p.label)
+		case !samePath(p.got, p.want, p.alsoData):
+			wrong = append(wrong, fmt.Sprintf("%s is %q, want %q", p.label, p.got, p.want))
+		default:
+			seen = append(seen, fmt.Sprintf("%s %s", p.label, p.got))
+		}
+	}
+	switch {
+	case len(wrong) > 0:
+		c.Status, c.Detail = Fail, strings.Join(wrong, "; ")
+		c.Fix = "check for a wrapper script or shell profile that overrides TASKRC, TASKDATA or TIMEWARRIORDB"
+	case len(unknown) > 0:
+		c.Status = Warn
+		c.Detail = "the diagnostics reports do not show the " + strings.Join(unknown, ", ")
+		c.Fix = "run `task diagnostics` and `timew diagnostics` to check the paths by hand"
+	default:
+		c.Status, c.Detail = Pass, strings.Join(seen, ", ")
+	}
+	return c
+}
+
+func RegisterHandlers(s *server.MCPServer) {
+	s.AddTool(mcp.NewTool("warmcp_doctor",
+		mcp.WithDescription("Check the Taskwarrior and Timewarrior setup: binaries and versions, taskrc and timew config, writable data directories, hooks (including the Timewarrior on-modify hook), sync settings, date parsing and the files task and timew actually resolve. Returns a pass/warn/fail checklist with a fix for each problem. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), doctorHandler)
+}
+
+func doctorHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	data, err := json.MarshalIndent(Run(ctx), "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,118 @@
+package doctor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"warmcp/pkg/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRunner struct {
+	show      string
+	taskDiag  string
+	timewDiag string
+}
+
+func (m *mockRunner) Run(ctx context.Context, name string, env []string, baseArgs []string, args ...string) (string, error) {
+	switch {
+	case len(args) == 1 && args[0] == "--version":
+		if strings.HasSuffix(name, "timew") {
+			return "1.7.1", nil
+		}
+		return "3.1.0", nil
+	case len(args) == 1 && args[0] == "diagnostics":
+		if strings.HasSuffix(name, "timew") {
+			return m.timewDiag, nil
+		}
+		return m.taskDiag, nil
+	case len(args) > 0 && args[0] == "_show":
+		return m.show, nil
+	case len(args) > 0 && args[0] == "calc":
+		return args[len(args)-1], nil
+	}
+	return "", nil
+}
+
+func writeFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.NoError(t, os.WriteFile(path, []byte(content), mode))
+}
+
+func checks(r Report) map[string]Check {
+	byName := map[string]Check{}
+	for _, c := range r.Checks {
+		byName[c.Name] = c
+	}
+	return byName
+}
+
+func TestDoctor(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "bin", "task"), "#!/bin/sh\n", 0o755)
+	writeFile(t, filepath.Join(dir, "bin", "timew"), "#!/bin/sh\n", 0o755)
+	writeFile(t, filepath.Join(dir, "taskrc"), "data.location="+filepath.Join(dir, "task")+"\n", 0o644)
+	writeFile(t, filepath.Join(dir, "task", "hooks", "on-modify.timewarrior"), "#!/bin/sh\n", 0o755)
+	writeFile(t, filepath.Join(dir, "timew", "timewarrior.cfg"), "verbose = yes\nexclusions:\n  monday = <9:00\nnonsense\n", 0o644)
+
+	cfg := common.DefaultConfig()
+	cfg.Binaries = common.Binaries{Task: filepath.Join(dir, "bin", "task"), Timew: filepath.Join(dir, "bin", "timew")}
+	cfg.Profiles = map[string]common.Profile{"work": {
+		Name:          "work",
+		Taskrc:        filepath.Join(dir, "taskrc"),
+		Taskdata:      filepath.Join(dir, "task"),
+		TimewarriorDB: filepath.Join(dir, "timew"),
+	}}
+	assert.NoError(t, common.SetConfig(cfg))
+	defer common.SetConfig(common.DefaultConfig())
+
+	mock := &mockRunner{
+		show: "data.location=" + filepath.Join(dir, "task") + "\nsync.server.url=https://sync.example.com\n",
+		taskDiag: "task 3.1.0\n   Platform: Linux\n\nConfiguration\n       File: " + filepath.Join(dir, "taskrc") +
+			" (found), 40 bytes, mode 100644\n       Data: " + filepath.Join(dir, "task") + " (found), dir, mode 40755\n",
+		timewDiag: "Timewarrior 1.7.1\n\n   TIMEWARRIORDB: " + filepath.Join(dir, "timew") + "\n            Cfg: " +
+			filepath.Join(dir, "timew", "timewarrior.cfg") + " (found), 60 bytes, mode 100644\n       Database: " +
+			filepath.Join(dir, "timew", "data") + " (found), 4096 bytes, mode 40755\n",
+	}
+	common.Runner = mock
+	before := common.CurrentCapabilities()
+	work, err := common.LookupProfile("work")
+	assert.NoError(t, err)
+	ctx := common.WithProfile(context.Background(), work)
+	r := Run(ctx)
+
+	byName := checks(r)
+	assert.Equal(t, "work", r.Profile)
+	assert.Equal(t, Pass, byName["task binary"].Status)
+	assert.Contains(t, byName["task binary"].Detail, "3.1.0")
+	assert.Equal(t, Pass, byName["taskrc"].Status)
+	assert.Equal(t, Fail, byName["timew config"].Status)
+	assert.Contains(t, byName["timew config"].Detail, `line 4: "nonsense"`)
+	assert.Equal(t, Pass, byName["task data directory"].Status)
+	assert.Equal(t, Pass, byName["timew database"].Status)
+	assert.Equal(t, Pass, byName["hooks"].Status)
+	assert.Equal(t, Fail, byName["sync"].Status)
+	assert.Contains(t, byName["sync"].Detail, "missing sync.server.client_id, sync.encryption_secret")
+	assert.Equal(t, Pass, byName["date format"].Status)
+	assert.Equal(t, Pass, byName["process environment"].Status)
+	assert.Equal(t, Fail, r.Status)
+	assert.Equal(t, before, common.CurrentCapabilities())
+
+	// A wrapper that resets TASKRC sends task to the wrong database.
+	writeFile(t, filepath.Join(dir, "timew", "timewarrior.cfg"), "verbose = yes\n", 0o644)
+	mock.show = "data.location=" + filepath.Join(dir, "task") + "\n"
+	mock.taskDiag = strings.Replace(mock.taskDiag, filepath.Join(dir, "taskrc"), "/home/me/.taskrc", 1)
+	assert.NoError(t, os.Chmod(filepath.Join(dir, "task", "hooks", "on-modify.timewarrior"), 0o644))
+	r = Run(ctx)
+	byName = checks(r)
+	assert.Equal(t, Pass, byName["timew config"].Status)
+	assert.Equal(t, Pass, byName["sync"].Status)
+	assert.Equal(t, Fail, byName["hooks"].Status)
+	assert.Contains(t, byName["hooks"].Fix, "chmod +x")
+	assert.Equal(t, Fail, byName["process environment"].Status)
+	assert.Contains(t, byName["process environment"].Detail, `task config is "/home/me/.taskrc"`)
+}
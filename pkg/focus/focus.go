@@ -0,0 +1,404 @@
+package focus
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"warmcp/pkg/common"
+	"warmcp/pkg/taskwarrior"
+	"warmcp/pkg/timewarrior"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const (
+	defaultMinutes          = 25
+	defaultBreakMinutes     = 5
+	defaultLongBreakMinutes = 15
+	// longBreakEvery is how many completed sessions in a day earn a long break.
+	longBreakEvery = 4
+)
+
+// now and afterFunc are the clock and timer used by sessions; tests replace them.
+var (
+	now       = time.Now
+	afterFunc = func(d time.Duration, f func()) stopper { return time.AfterFunc(d, f) }
+)
+
+type stopper interface {
+	Stop() bool
+}
+
+// Session is one focus session bound to a task.
+type Session struct {
+	TaskUUID    string    `json:"task_uuid"`
+	Description string    `json:"description"`
+	Tags        []string  `json:"tags"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Minutes     int       `json:"minutes"`
+	Completed   bool      `json:"completed"`
+	Note        string    `json:"note,omitempty"`
+	// Profile is the warmcp profile whose Timewarrior database tracks the session.
+	Profile string `json:"profile,omitempty"`
+
+	profile                        common.Profile
+	breakMinutes, longBreakMinutes int
+}
+
+// Manager runs at most one focus session at a time and keeps the history of
+// the sessions it has run since the server started.
+type Manager struct {
+	// tracking serializes the manager's timew commands, so that a session
+	// is started and stopped in order without holding mu while they run.
+	tracking   sync.Mutex
+	mu         sync.Mutex
+	active     *Session
+	timer      stopper
+	breakTimer stopper
+	breakUntil time.Time
+	history    []Session
+	notify     func(method string, params map[string]any)
+}
+
+// NewManager returns a Manager that reports session events through notify.
+func NewManager(notify func(method string, params map[string]any)) *Manager {
+	return &Manager{notify: notify}
+}
+
+// send emits an MCP log message notification, which clients surface to the user.
+func (m *Manager) send(event, message string, s Session) {
+	if m.notify == nil {
+		return
+	}
+	m.notify("notifications/message", map[string]any{
+		"level":  "notice",
+		"logger": "warmcp.focus",
+		"data": map[string]any{
+			"event":     event,
+			"message":   message,
+			"task_uuid": s.TaskUUID,
+		},
+	})
+}
+
+// Start begins a session in the profile of ctx, tracking it in Timewarrior
+// with the given tags.
+func (m *Manager) Start(ctx context.Context, s Session) error {
+	m.tracking.Lock()
+	defer m.tracking.Unlock()
+	m.mu.Lock()
+	active := m.active
+	m.mu.Unlock()
+	if active != nil {
+		return fmt.Errorf("a focus session on %q is already running until %s", active.Description, active.End.Format("15:04"))
+	}
+	// timew start would silently stop what the user is tracking.
+	if tags, tracking, err := timewarrior.ActiveTags(ctx); err != nil {
+		return err
+	} else if tracking {
+		return fmt.Errorf("already tracking %q in Timewarrior: stop it before starting a focus session", strings.Join(tags, " "))
+	}
+	if _, err := timewarrior.Start(ctx, s.Tags...); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.breakTimer != nil {
+		m.breakTimer.Stop()
+		m.breakTimer = nil
+	}
+	m.breakUntil = time.Time{}
+	s.profile = common.CurrentProfile(ctx)
+	s.Profile = s.profile.Name
+	s.Start = now()
+	s.End = s.Start.Add(time.Duration(s.Minutes) * time.Minute)
+	m.active = &s
+	m.timer = afterFunc(s.End.Sub(s.Start), func() { m.expire(&s) })
+	return nil
+}
+
+// completedToday counts the completed sessions that started today in loc.
+func (m *Manager) completedToday(loc *time.Location) int {
+	today := now().In(loc).Format(common.DayLayout)
+	n := 0
+	for _, s := range m.history {
+		if s.Completed && s.Start.In(loc).Format(common.DayLayout) == today {
+			n++
+		}
+	}
+	return n
+}
+
+// expire runs when the time of session s is up. Timewarrior is stopped in
+// the profile the session was started in.
+func (m *Manager) expire(s *Session) {
+	m.finish(common.WithProfile(context.Background(), s.profile), s)
+}
+
+// finish ends session s and starts the break. It does nothing if s is no
+// longer the active session, because it was stopped and perhaps replaced
+// while its timer fired.
+func (m *Manager) finish(ctx context.Context, expected *Session) {
+	m.tracking.Lock()
+	defer m.tracking.Unlock()
+	m.mu.Lock()
+	if m.active != expected {
+		m.mu.Unlock()
+		return
+	}
+	s := *m.active
+	m.active = nil
+	m.mu.Unlock()
+
+	s.Completed = true
+	s.Note = stopTracking(ctx, s)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.history = append(m.history, s)
+	breakMinutes := s.breakMinutes
+	if m.completedToday(common.DefaultLocation())%longBreakEvery == 0 {
+		breakMinutes = s.longBreakMinutes
+	}
+	if breakMinutes <= 0 {
+		m.send("session_complete", fmt.Sprintf("Focus session on %q complete.", s.Description), s)
+		return
+	}
+	m.breakUntil = now().Add(time.Duration(breakMinutes) * time.Minute)
+	m.send("break_due", fmt.Sprintf("Focus session on %q complete. Take a %d minute break.", s.Description, breakMinutes), s)
+	m.breakTimer = afterFunc(time.Duration(breakMinutes)*time.Minute, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.breakUntil = time.Time{}
+		m.breakTimer = nil
+		m.send("break_over", "Break over. Ready for the next focus session.", s)
+	})
+}
+
+// Stop abandons the active session. It is recorded but does not count as completed.
+func (m *Manager) Stop(ctx context.Context) (Session, error) {
+	m.tracking.Lock()
+	defer m.tracking.Unlock()
+	m.mu.Lock()
+	if m.active == nil {
+		m.mu.Unlock()
+		return Session{}, fmt.Errorf("no focus session is running")
+	}
+	if p := common.CurrentProfile(ctx).Name; p != m.active.Profile {
+		m.mu.Unlock()
+		return Session{}, fmt.Errorf("the focus session runs in profile %q, not %q", m.active.Profile, p)
+	}
+	m.timer.Stop()
+	s := *m.active
+	m.active = nil
+	m.mu.Unlock()
+
+	s.End = now()
+	s.Note = stopTracking(ctx, s)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.history = append(m.history, s)
+	return s, nil
+}
+
+// stopTracking stops the Timewarrior interval of session s and returns a note
+// for the history if it did not. An interval that no longer carries the
+// session's tags was started by the user since, and is left running.
+func stopTracking(ctx context.Context, s Session) string {
+	tags, active, err := timewarrior.ActiveTags(ctx)
+	if err != nil {
+		return "timew stop skipped: " + err.Error()
+	}
+	if !active {
+		return ""
+	}
+	for _, tag := range s.Tags {
+		if !slices.Contains(tags, tag) {
+			return "timew stop skipped: Timewarrior is tracking something else now"
+		}
+	}
+	if _, err := timewarrior.Stop(ctx); err != nil {
+		return "timew stop failed: " + err.Error()
+	}
+	return ""
+}
+
+// TaskCount sums the sessions spent on one task today.
+type TaskCount struct {
+	TaskUUID    string `json:"task_uuid"`
+	Description string `json:"description"`
+	Completed   int    `json:"completed"`
+	Abandoned   int    `json:"abandoned"`
+	Minutes     int    `json:"minutes"`
+}
+
+// ActiveSession is the running session with its remaining time.
+type ActiveSession struct {
+	Session
+	RemainingSeconds int `json:"remaining_seconds"`
+}
+
+// Status is the focus_status result.
+type Status struct {
+	Active         *ActiveSession `json:"active,omitempty"`
+	BreakUntil     *time.Time     `json:"break_until,omitempty"`
+	CompletedToday int            `json:"completed_today"`
+	NextBreak      string         `json:"next_break"`
+	Today          []TaskCount    `json:"today"`
+}
+
+// Status reports the active session, any break in progress and the counts per
+// task for today, with the day taken in loc.
+func (m *Manager) Status(loc *time.Location) Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := now().In(loc)
+	st := Status{CompletedToday: m.completedToday(loc), NextBreak: "short", Today: []TaskCount{}}
+	if (st.CompletedToday+1)%longBreakEvery == 0 {
+		st.NextBreak = "long"
+	}
+	if m.active != nil {
+		remaining := m.active.End.Sub(t)
+		if remaining < 0 {
+			remaining = 0
+		}
+		st.Active = &ActiveSession{Session: *m.active, RemainingSeconds: int(remaining.Seconds())}
+		st.Active.Start, st.Active.End = st.Active.Start.In(loc), st.Active.End.In(loc)
+	}
+	if m.breakUntil.After(t) {
+		until := m.breakUntil.In(loc)
+		st.BreakUntil = &until
+	}
+
+	today := t.Format(common.DayLayout)
+	index := map[string]int{}
+	for _, s := range m.history {
+		if s.Start.In(loc).Format(common.DayLayout) != today {
+			continue
+		}
+		i, ok := index[s.TaskUUID]
+		if !ok {
+			i = len(st.Today)
+			index[s.TaskUUID] = i
+			st.Today = append(st.Today, TaskCount{TaskUUID: s.TaskUUID, Description: s.Description})
+		}
+		if s.Completed {
+			st.Today[i].Completed++
+		} else {
+			st.Today[i].Abandoned++
+		}
+		st.Today[i].Minutes += int(s.End.Sub(s.Start).Minutes())
+	}
+	sort.SliceStable(st.Today, func(a, b int) bool { return st.Today[a].Minutes > st.Today[b].Minutes })
+	return st
+}
+
+// timewTags follows the Taskwarrior hook convention of tracking a task under
+// its description, project and tags.
+func timewTags(t taskwarrior.Task, extra []string) []string {
+	tags := []string{t.Description}
+	if t.Project != "" {
+		tags = append(tags, t.Project)
+	}
+	tags = append(tags, t.Tags...)
+	return append(tags, extra...)
+}
+
+func intArg(argsMap map[string]any, name string, def int) (int, error) {
+	v, ok := argsMap[name].(float64)
+	if !ok {
+		return def, nil
+	}
+	if v < 0 || v != float64(int(v)) {
+		return 0, fmt.Errorf("%s must be a whole number of minutes", name)
+	}
+	return int(v), nil
+}
+
+func RegisterHandlers(s *server.MCPServer) {
+	m := NewManager(s.SendNotificationToAllClients)
+
+	s.AddTool(mcp.NewTool("focus_start",
+		mcp.WithDescription("Start a focus (pomodoro) session on a task, tracked in Timewarrior. Refused while Timewarrior is tracking something else. The session stops itself after the given length and a notification announces the break. PROMPT FOR CONFIRMATION."),
+		mcp.WithString("task_uuid", mcp.Required(), mcp.Description("UUID of the task to focus on")),
+		mcp.WithNumber("minutes", mcp.Description(fmt.Sprintf("Session length in minutes. Default: %d", defaultMinutes))),
+		mcp.WithNumber("break_minutes", mcp.Description(fmt.Sprintf("Short break length. Default: %d", defaultBreakMinutes))),
+		mcp.WithNumber("long_break_minutes", mcp.Description(fmt.Sprintf("Break after every %d completed sessions. Default: %d", longBreakEvery, defaultLongBreakMinutes))),
+		mcp.WithArray("tags", mcp.WithStringItems(), mcp.Description("Extra Timewarrior tags, added to the task's description, project and tags")),
+	), m.startHandler)
+
+	s.AddTool(mcp.NewTool("focus_status",
+		mcp.WithDescription("Show the running focus session with its remaining time, any break in progress, and today's session counts per task. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		common.WithTimezone(),
+	), m.statusHandler)
+
+	s.AddTool(mcp.NewTool("focus_stop",
+		mcp.WithDescription("Abandon the running focus session early and stop its Timewarrior tracking. PROMPT FOR CONFIRMATION."),
+	), m.stopHandler)
+}
+
+func (m *Manager) startHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	uuid, _ := argsMap["task_uuid"].(string)
+	if uuid == "" {
+		return mcp.NewToolResultError("task_uuid is required"), nil
+	}
+	s := Session{TaskUUID: uuid}
+	var err error
+	if s.Minutes, err = intArg(argsMap, "minutes", defaultMinutes); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if s.Minutes == 0 {
+		return mcp.NewToolResultError("minutes must be positive"), nil
+	}
+	if s.breakMinutes, err = intArg(argsMap, "break_minutes", defaultBreakMinutes); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if s.longBreakMinutes, err = intArg(argsMap, "long_break_minutes", defaultLongBreakMinutes); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	tasks, err := taskwarrior.ExportTasks(ctx, uuid)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(tasks) != 1 {
+		return mcp.NewToolResultError(fmt.Sprintf("no task with uuid %s", uuid)), nil
+	}
+	s.Description = tasks[0].Description
+	var extra []string
+	if raw, ok := argsMap["tags"].([]any); ok {
+		for _, v := range raw {
+			if tag, ok := v.(string); ok && tag != "" {
+				extra = append(extra, tag)
+			}
+		}
+	}
+	s.Tags = timewTags(tasks[0], extra)
+
+	if err := m.Start(ctx, s); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return common.JSONResult(m.Status(common.Location(ctx)))
+}
+
+func (m *Manager) statusHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return common.JSONResult(m.Status(common.Location(ctx)))
+}
+
+func (m *Manager) stopHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	s, err := m.Stop(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return common.JSONResult(s)
+}
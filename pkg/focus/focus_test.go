@@ -0,0 +1,145 @@
+package focus
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRunner struct {
+	calls [][]string
+	// tracked holds the tags of the open Timewarrior interval, nil when nothing is tracked.
+	tracked []string
+}
+
+func (m *mockRunner) Run(ctx context.Context, name string, env []string, baseArgs []string, args ...string) (string, error) {
+	if name == "task" {
+		m.calls = append(m.calls, append([]string{name}, args...))
+		return `[{"uuid":"9d6a2c1e-8b1f-4c4e-9a55-1c2b3d4e5f60","description":"Write report","project":"Work","tags":["deep"],"status":"pending"}]`, nil
+	}
+	switch {
+	case args[0] == "get" && args[1] == "dom.active":
+		return map[bool]string{true: "1", false: "0"}[m.tracked != nil], nil
+	case args[0] == "get" && args[1] == "dom.active.tag.count":
+		return strconv.Itoa(len(m.tracked)), nil
+	case args[0] == "get":
+		n, _ := strconv.Atoi(strings.TrimPrefix(args[1], "dom.active.tag."))
+		return m.tracked[n-1], nil
+	case args[0] == "start":
+		m.tracked = args[1:]
+	case args[0] == "stop":
+		m.tracked = nil
+	}
+	m.calls = append(m.calls, append([]string{name}, args...))
+	return "", nil
+}
+
+type fakeTimer struct {
+	d       time.Duration
+	f       func()
+	stopped bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.stopped = true
+	return true
+}
+
+func TestFocusSession(t *testing.T) {
+	origNow, origAfterFunc := now, afterFunc
+	defer func() { now, afterFunc = origNow, origAfterFunc }()
+	clock := time.Date(2024, 1, 8, 9, 0, 0, 0, time.Local)
+	now = func() time.Time { return clock }
+	var timers []*fakeTimer
+	afterFunc = func(d time.Duration, f func()) stopper {
+		timer := &fakeTimer{d: d, f: f}
+		timers = append(timers, timer)
+		return timer
+	}
+
+	runner := &mockRunner{}
+	common.Runner = runner
+	var notes []map[string]any
+	m := NewManager(func(method string, params map[string]any) {
+		assert.Equal(t, "notifications/message", method)
+		notes = append(notes, params["data"].(map[string]any))
+	})
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"task_uuid": "9d6a2c1e-8b1f-4c4e-9a55-1c2b3d4e5f60", "minutes": 30.0, "tags": []any{"pomodoro"}}
+	res, err := m.startHandler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.False(t, res.IsError)
+	assert.Equal(t, []string{"timew", "start", "Write report", "Work", "deep", "pomodoro"}, runner.calls[1])
+	assert.Equal(t, 30*time.Minute, timers[0].d)
+
+	res, _ = m.startHandler(context.Background(), req)
+	assert.True(t, res.IsError)
+	assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "already running until 09:30")
+
+	clock = clock.Add(10 * time.Minute)
+	st := m.Status(time.Local)
+	assert.Equal(t, 20*60, st.Active.RemainingSeconds)
+
+	// The timer fires: tracking stops and a break is announced.
+	clock = clock.Add(20 * time.Minute)
+	timers[0].f()
+	assert.Equal(t, []string{"timew", "stop"}, runner.calls[len(runner.calls)-1])
+	assert.Equal(t, "break_due", notes[0]["event"])
+	assert.Contains(t, notes[0]["message"], "Take a 5 minute break")
+
+	res, _ = m.statusHandler(context.Background(), mcp.CallToolRequest{})
+	var status Status
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(mcp.TextContent).Text), &status))
+	assert.Nil(t, status.Active)
+	assert.NotNil(t, status.BreakUntil)
+	assert.Equal(t, 1, status.CompletedToday)
+	assert.Equal(t, []TaskCount{{TaskUUID: "9d6a2c1e-8b1f-4c4e-9a55-1c2b3d4e5f60", Description: "Write report", Completed: 1, Minutes: 30}}, status.Today)
+
+	timers[1].f()
+	assert.Equal(t, "break_over", notes[1]["event"])
+	assert.Nil(t, m.Status(time.Local).BreakUntil)
+
+	// An abandoned session is recorded but not counted as completed.
+	m.startHandler(context.Background(), req)
+	clock = clock.Add(5 * time.Minute)
+	res, _ = m.stopHandler(context.Background(), mcp.CallToolRequest{})
+	assert.False(t, res.IsError)
+	assert.True(t, timers[2].stopped)
+	st = m.Status(time.Local)
+	assert.Equal(t, 1, st.Today[0].Abandoned)
+	assert.Equal(t, 35, st.Today[0].Minutes)
+
+	res, _ = m.stopHandler(context.Background(), mcp.CallToolRequest{})
+	assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "no focus session")
+
+	// The timer of the stopped session fires late: the next session keeps running.
+	m.startHandler(context.Background(), req)
+	calls := len(runner.calls)
+	timers[2].f()
+	assert.Len(t, runner.calls, calls)
+	assert.NotNil(t, m.Status(time.Local).Active)
+
+	// The user switched Timewarrior to something else: that interval keeps running.
+	runner.tracked = []string{"meeting"}
+	res, _ = m.stopHandler(context.Background(), mcp.CallToolRequest{})
+	var stopped Session
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(mcp.TextContent).Text), &stopped))
+	assert.Contains(t, stopped.Note, "tracking something else")
+	assert.Len(t, runner.calls, calls)
+	assert.Equal(t, []string{"meeting"}, runner.tracked)
+
+	// A new session does not stop the other interval.
+	res, _ = m.startHandler(context.Background(), req)
+	assert.True(t, res.IsError)
+	assert.Contains(t, res.Content[0].(mcp.TextContent).Text, `already tracking "meeting"`)
+	assert.Equal(t, "task", runner.calls[len(runner.calls)-1][0])
+	assert.Equal(t, []string{"meeting"}, runner.tracked)
+}
@@ -0,0 +1,325 @@
+package prompts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"warmcp/pkg/common"
+	"warmcp/pkg/taskwarrior"
+	"warmcp/pkg/timewarrior"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// now is the clock used for the default ranges; tests replace it.
+var now = time.Now
+
+// resourceURI names a resource embedded in a prompt. The resources are not
+// readable on their own, so they live under warmcp://prompt/ rather than
+// looking like task:// or timew:// resources the server does not serve.
+func resourceURI(prompt, part string) string {
+	return "warmcp://prompt/" + prompt + "/" + part
+}
+
+// taskResource embeds the tasks matching filter, as `task export` would give them.
+func taskResource(ctx context.Context, uri string, filter []string) (mcp.PromptMessage, error) {
+	tasks, err := taskwarrior.ExportTasks(ctx, filter...)
+	if err != nil {
+		return mcp.PromptMessage{}, err
+	}
+	if tasks == nil {
+		tasks = []taskwarrior.Task{}
+	}
+	return jsonResource(uri+"?filter="+url.QueryEscape(strings.Join(filter, " ")), tasks)
+}
+
+// jsonResource embeds v as JSON, with task and timew dates in the default timezone.
+func jsonResource(uri string, v any) (mcp.PromptMessage, error) {
+	data, err := json.Marshal(v)
+	if err == nil {
+		data, err = common.LocalizeJSON(data, common.DefaultLocation())
+	}
+	var text bytes.Buffer
+	if err == nil {
+		err = json.Indent(&text, data, "", "  ")
+	}
+	if err != nil {
+		return mcp.PromptMessage{}, err
+	}
+	return mcp.NewPromptMessage(mcp.RoleUser, mcp.NewEmbeddedResource(mcp.TextResourceContents{
+		URI:      uri,
+		MIMEType: "application/json",
+		Text:     text.String(),
+	})), nil
+}
+
+// intervalsResource embeds tracked intervals, labelling uri with rangeArgs.
+func intervalsResource(uri string, intervals []timewarrior.Interval, rangeArgs ...string) (mcp.PromptMessage, error) {
+	if intervals == nil {
+		intervals = []timewarrior.Interval{}
+	}
+	return jsonResource(uri+"?range="+url.QueryEscape(strings.Join(rangeArgs, " ")), intervals)
+}
+
+// TagTime is the time tracked under one tag.
+type TagTime struct {
+	Tag     string  `json:"tag"`
+	Hours   float64 `json:"hours"`
+	Entries int     `json:"entries"`
+}
+
+// TimeSummary totals a set of intervals, so the model does not have to add up durations.
+type TimeSummary struct {
+	TotalHours float64   `json:"total_hours"`
+	Intervals  int       `json:"intervals"`
+	Untagged   []int     `json:"untagged_ids"`
+	Open       []int     `json:"open_ids"`
+	ByTag      []TagTime `json:"by_tag"`
+}
+
+func hours(d time.Duration) float64 {
+	return float64(d.Round(time.Minute)) / float64(time.Hour)
+}
+
+// Summarize totals intervals by tag. An interval counts fully towards each of its tags.
+func Summarize(intervals []timewarrior.Interval) TimeSummary {
+	sum := TimeSummary{Intervals: len(intervals), Untagged: []int{}, Open: []int{}, ByTag: []TagTime{}}
+	var total time.Duration
+	byTag := map[string]time.Duration{}
+	entries := map[string]int{}
+	for _, i := range intervals {
+		d := i.Duration()
+		total += d
+		if len(i.Tags) == 0 {
+			sum.Untagged = append(sum.Untagged, i.ID)
+		}
+		if i.Open() {
+			sum.Open = append(sum.Open, i.ID)
+		}
+		for _, tag := range i.Tags {
+			byTag[tag] += d
+			entries[tag]++
+		}
+	}
+	for tag, d := range byTag {
+		sum.ByTag = append(sum.ByTag, TagTime{Tag: tag, Hours: hours(d), Entries: entries[tag]})
+	}
+	sort.Slice(sum.ByTag, func(a, b int) bool {
+		if sum.ByTag[a].Hours != sum.ByTag[b].Hours {
+			return sum.ByTag[a].Hours > sum.ByTag[b].Hours
+		}
+		return sum.ByTag[a].Tag < sum.ByTag[b].Tag
+	})
+	sum.TotalHours = hours(total)
+	return sum
+}
+
+// inProfile builds a prompt in the profile named by the `profile` argument.
+// Prompts do not pass through the tool middleware, so they select it themselves.
+func inProfile(ctx context.Context, req mcp.GetPromptRequest, build func(ctx context.Context, args map[string]string) (*mcp.GetPromptResult, error)) (*mcp.GetPromptResult, error) {
+	p, err := common.LookupProfile(req.Params.Arguments["profile"])
+	if err != nil {
+		return nil, err
+	}
+	return build(common.WithProfile(ctx, p), req.Params.Arguments)
+}
+
+func prompt(description, instructions string, resources ...mcp.PromptMessage) *mcp.GetPromptResult {
+	messages := []mcp.PromptMessage{mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(instructions))}
+	return &mcp.GetPromptResult{Description: description, Messages: append(messages, resources...)}
+}
+
+func profileArgument() mcp.PromptOption {
+	return mcp.WithArgument("profile", mcp.ArgumentDescription("Named profile (database) to read, as listed by warmcp_profiles. Default: the default profile"))
+}
+
+func RegisterHandlers(s *server.MCPServer) {
+	s.AddPrompt(mcp.NewPrompt("weekly_review",
+		mcp.WithPromptDescription("Weekly review with the last seven days of completed tasks, the open task list and tracked time attached."),
+		mcp.WithArgument("project", mcp.ArgumentDescription("Limit the review to one project")),
+		profileArgument(),
+	), weeklyReviewHandler)
+
+	s.AddPrompt(mcp.NewPrompt("inbox_triage",
+		mcp.WithPromptDescription("Triage the pending tasks carrying an inbox tag, with the existing projects and tags attached for filing them."),
+		mcp.WithArgument("tag", mcp.ArgumentDescription("Inbox tag. Default: inbox")),
+		profileArgument(),
+	), inboxTriageHandler)
+
+	s.AddPrompt(mcp.NewPrompt("standup",
+		mcp.WithPromptDescription("Standup notes from what was completed and tracked since a point in time, what is active and what is blocked."),
+		mcp.WithArgument("since", mcp.ArgumentDescription("Start of the period, as a date expression such as yesterday, monday or 2024-01-15. Default: yesterday")),
+		profileArgument(),
+	), standupHandler)
+
+	s.AddPrompt(mcp.NewPrompt("time_audit",
+		mcp.WithPromptDescription("Audit tracked time in a range: totals per tag, untagged and open intervals, and the intervals themselves."),
+		mcp.WithArgument("range", mcp.RequiredArgument(), mcp.ArgumentDescription("Timewarrior range, such as :week, :lastmonth or 2024-01-01 - 2024-01-08")),
+		profileArgument(),
+	), timeAuditHandler)
+}
+
+func weeklyReviewHandler(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return inProfile(ctx, req, func(ctx context.Context, args map[string]string) (*mcp.GetPromptResult, error) {
+		project := args["project"]
+		scope := []string{}
+		subject := "all projects"
+		if project != "" {
+			scope = append(scope, "project:"+project)
+			subject = "project " + project
+		}
+		// Completed tasks and tracked time cover the same seven days, today included.
+		t := now().In(common.DefaultLocation())
+		from := common.StartOfDay(t, t.Location()).AddDate(0, 0, -6)
+		completed, err := taskResource(ctx, resourceURI("weekly_review", "completed"), append([]string{"status:completed", "end.after:" + common.CommandDate(from)}, scope...))
+		if err != nil {
+			return nil, err
+		}
+		pending, err := taskResource(ctx, resourceURI("weekly_review", "pending"), append([]string{"status:pending"}, scope...))
+		if err != nil {
+			return nil, err
+		}
+		intervals, err := timewarrior.ExportBetween(ctx, from, t)
+		if err != nil {
+			return nil, err
+		}
+		if project != "" {
+			var matching []timewarrior.Interval
+			for _, i := range intervals {
+				for _, tag := range i.Tags {
+					if tag == project || strings.HasPrefix(tag, project+".") {
+						matching = append(matching, i)
+						break
+					}
+				}
+			}
+			intervals = matching
+		}
+		tracked, err := jsonResource(resourceURI("weekly_review", "time")+"?since="+url.QueryEscape(from.Format(common.DayLayout)), Summarize(intervals))
+		if err != nil {
+			return nil, err
+		}
+		return prompt("Weekly review of "+subject,
+			fmt.Sprintf("Let's do my weekly review of %s. Attached are the tasks I completed in the last seven days, "+
+				"my open tasks and a summary of the time I tracked since %s. "+
+				"Summarize what got done and where the time went, then go through the open tasks: point out overdue, stale or "+
+				"unclear ones and those without a due date or priority, and suggest what to drop, defer or schedule for next week. "+
+				"Ask me before changing anything.", subject, from.Format(common.DayLayout)),
+			completed, pending, tracked), nil
+	})
+}
+
+func inboxTriageHandler(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return inProfile(ctx, req, func(ctx context.Context, args map[string]string) (*mcp.GetPromptResult, error) {
+		tag := strings.TrimPrefix(args["tag"], "+")
+		if tag == "" {
+			tag = "inbox"
+		}
+		inbox, err := taskResource(ctx, resourceURI("inbox_triage", "inbox"), []string{"status:pending", "+" + tag})
+		if err != nil {
+			return nil, err
+		}
+		// The open tasks show which projects and tags are in use for filing.
+		tasks, err := taskwarrior.ExportTasks(ctx, "status:pending")
+		if err != nil {
+			return nil, err
+		}
+		projects, tags := map[string]int{}, map[string]int{}
+		for _, t := range tasks {
+			if t.Project != "" {
+				projects[t.Project]++
+			}
+			for _, tg := range t.Tags {
+				if tg != tag {
+					tags[tg]++
+				}
+			}
+		}
+		filing, err := jsonResource(resourceURI("inbox_triage", "filing"), map[string]any{"projects": projects, "tags": tags})
+		if err != nil {
+			return nil, err
+		}
+		return prompt("Inbox triage of +"+tag,
+			fmt.Sprintf("Help me triage my inbox: the pending tasks tagged +%s are attached, with the projects and tags "+
+				"already in use and how many open tasks each has. For each inbox task, suggest a clearer description if needed, "+
+				"a project, tags, a priority and a due or scheduled date, or whether to delete it, and remove the +%s tag once "+
+				"it is filed. Propose the changes as a list and ask me to confirm before applying them with task_modify.", tag, tag),
+			inbox, filing), nil
+	})
+}
+
+func standupHandler(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return inProfile(ctx, req, func(ctx context.Context, args map[string]string) (*mcp.GetPromptResult, error) {
+		since := args["since"]
+		if since == "" {
+			since = "yesterday"
+		}
+		loc := common.DefaultLocation()
+		resolved, err := taskwarrior.ResolveDate(ctx, since, loc)
+		if err != nil {
+			return nil, err
+		}
+		from, err := time.Parse(time.RFC3339, resolved.ISO)
+		if err != nil {
+			return nil, err
+		}
+		done, err := taskResource(ctx, resourceURI("standup", "done"), []string{"status:completed", "end.after:" + resolved.Taskwarrior})
+		if err != nil {
+			return nil, err
+		}
+		active, err := taskResource(ctx, resourceURI("standup", "active"), []string{"status:pending", "+ACTIVE"})
+		if err != nil {
+			return nil, err
+		}
+		blocked, err := taskResource(ctx, resourceURI("standup", "blocked"), []string{"status:pending", "+BLOCKED"})
+		if err != nil {
+			return nil, err
+		}
+		intervals, err := timewarrior.ExportBetween(ctx, from, now())
+		if err != nil {
+			return nil, err
+		}
+		tracked, err := intervalsResource(resourceURI("standup", "time"), intervals, common.CommandDate(from), "-", common.CommandDate(now()))
+		if err != nil {
+			return nil, err
+		}
+		return prompt("Standup since "+resolved.ISO,
+			fmt.Sprintf("Write my standup update covering %s (%s) until now, from the attached data: tasks completed, "+
+				"tasks in progress, blocked tasks and the time I tracked. Use three short sections, Done, Doing and Blocked, "+
+				"mention time spent where it helps, and for each blocked task name what it is waiting on.", since, resolved.ISO),
+			done, active, blocked, tracked), nil
+	})
+}
+
+func timeAuditHandler(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	return inProfile(ctx, req, func(ctx context.Context, args map[string]string) (*mcp.GetPromptResult, error) {
+		rangeArgs := strings.Fields(args["range"])
+		if len(rangeArgs) == 0 {
+			return nil, fmt.Errorf("range is required, e.g. :week")
+		}
+		intervals, err := timewarrior.ExportIntervals(ctx, rangeArgs...)
+		if err != nil {
+			return nil, err
+		}
+		summary, err := jsonResource(resourceURI("time_audit", "summary")+"?range="+url.QueryEscape(strings.Join(rangeArgs, " ")), Summarize(intervals))
+		if err != nil {
+			return nil, err
+		}
+		raw, err := intervalsResource(resourceURI("time_audit", "intervals"), intervals, rangeArgs...)
+		if err != nil {
+			return nil, err
+		}
+		return prompt("Time audit for "+strings.Join(rangeArgs, " "),
+			fmt.Sprintf("Audit my tracked time for %s. Attached are totals per tag and the intervals themselves. "+
+				"Tell me where the time went, flag untagged and still-open intervals, overlapping or suspiciously long entries "+
+				"and inconsistent tag spellings, and suggest the timew commands to fix them. Run timew_gaps for untracked "+
+				"working hours if useful. Ask me before changing anything.", strings.Join(rangeArgs, " ")),
+			summary, raw), nil
+	})
+}
@@ -0,0 +1,116 @@
+package prompts
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRunner struct {
+	calls [][]string
+}
+
+func (m *mockRunner) Run(ctx context.Context, name string, env []string, baseArgs []string, args ...string) (string, error) {
+	m.calls = append(m.calls, append([]string{name}, args...))
+	joined := strings.Join(args, " ")
+	switch {
+	case name == "timew":
+		return `[{"id":2,"start":"20240123T090000Z","end":"20240123T103000Z","tags":["acme","dev"]},` +
+			`{"id":1,"start":"20240123T110000Z","end":"20240123T113000Z"}]`, nil
+	case strings.Contains(joined, "calc"):
+		return "2024-01-23T00:00:00", nil
+	case strings.Contains(joined, "+BLOCKED"):
+		return `[{"uuid":"b","description":"Deploy","status":"pending","depends":["a"]}]`, nil
+	case strings.Contains(joined, "status:completed"):
+		return `[{"uuid":"c","description":"Fix login","status":"completed","end":"20240123T150000Z"}]`, nil
+	}
+	return "[]", nil
+}
+
+func embedded(t *testing.T, msg mcp.PromptMessage) mcp.TextResourceContents {
+	t.Helper()
+	res, ok := msg.Content.(mcp.EmbeddedResource)
+	assert.True(t, ok)
+	text, ok := res.Resource.(mcp.TextResourceContents)
+	assert.True(t, ok)
+	return text
+}
+
+func TestStandupPrompt(t *testing.T) {
+	origNow, origLocal := now, time.Local
+	defer func() { now, time.Local = origNow, origLocal }()
+	time.Local = time.UTC
+	now = func() time.Time { return time.Date(2024, 1, 24, 9, 0, 0, 0, time.UTC) }
+	mock := &mockRunner{}
+	common.Runner = mock
+
+	req := mcp.GetPromptRequest{}
+	req.Params.Arguments = map[string]string{}
+	res, err := standupHandler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Len(t, res.Messages, 5)
+	assert.Contains(t, res.Messages[0].Content.(mcp.TextContent).Text, "yesterday (2024-01-23T00:00:00Z)")
+
+	done := embedded(t, res.Messages[1])
+	assert.Equal(t, "warmcp://prompt/standup/done?filter=status%3Acompleted+end.after%3A2024-01-23T00%3A00%3A00", done.URI)
+	assert.Contains(t, done.Text, "Fix login")
+	assert.Contains(t, done.Text, `"end": "2024-01-23T15:00:00Z"`)
+	assert.Contains(t, embedded(t, res.Messages[3]).Text, "Deploy")
+	tracked := embedded(t, res.Messages[4])
+	assert.True(t, strings.HasPrefix(tracked.URI, "warmcp://prompt/standup/time?range="))
+	assert.Contains(t, tracked.Text, `"acme"`)
+	assert.Contains(t, mock.calls, []string{"timew", "export", "2024-01-23T00:00:00", "-", "2024-01-24T09:00:00"})
+}
+
+func TestTimeAuditPrompt(t *testing.T) {
+	common.Runner = &mockRunner{}
+
+	req := mcp.GetPromptRequest{}
+	req.Params.Arguments = map[string]string{"range": ":week"}
+	res, err := timeAuditHandler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Len(t, res.Messages, 3)
+	summary := embedded(t, res.Messages[1])
+	assert.Equal(t, "warmcp://prompt/time_audit/summary?range=%3Aweek", summary.URI)
+	assert.Contains(t, summary.Text, `"total_hours": 2`)
+	assert.Contains(t, summary.Text, `"untagged_ids": [
+    1
+  ]`)
+
+	req.Params.Arguments = map[string]string{"range": ":week", "profile": "nope"}
+	_, err = timeAuditHandler(context.Background(), req)
+	assert.ErrorContains(t, err, `unknown profile "nope"`)
+
+	req.Params.Arguments = map[string]string{}
+	_, err = timeAuditHandler(context.Background(), req)
+	assert.Error(t, err)
+}
+
+func TestWeeklyReviewPrompt(t *testing.T) {
+	origNow, origLocal := now, time.Local
+	defer func() { now, time.Local = origNow, origLocal }()
+	time.Local = time.UTC
+	now = func() time.Time { return time.Date(2024, 1, 24, 9, 0, 0, 0, time.UTC) }
+	mock := &mockRunner{}
+	common.Runner = mock
+
+	res, err := weeklyReviewHandler(context.Background(), mcp.GetPromptRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, res.Messages, 4)
+	uris := []string{}
+	for _, msg := range res.Messages[1:] {
+		uris = append(uris, embedded(t, msg).URI)
+	}
+	assert.Equal(t, []string{
+		"warmcp://prompt/weekly_review/completed?filter=status%3Acompleted+end.after%3A2024-01-18T00%3A00%3A00",
+		"warmcp://prompt/weekly_review/pending?filter=status%3Apending",
+		"warmcp://prompt/weekly_review/time?since=2024-01-18",
+	}, uris)
+	// Completed tasks and tracked time cover the same days.
+	assert.Contains(t, mock.calls, []string{"timew", "export", "2024-01-18T00:00:00", "-", "2024-01-24T09:00:00"})
+}
@@ -0,0 +1,242 @@
+package taskwarrior
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Bucket is one period of an analytics series. Open and Overdue are measured
+// at the end of the period, which makes the Open series a burndown.
+type Bucket struct {
+	Start     string `json:"start"`
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+	Deleted   int    `json:"deleted"`
+	Open      int    `json:"open"`
+	Overdue   int    `json:"overdue"`
+}
+
+// GroupAnalytics holds the series and summary figures for one project or tag.
+type GroupAnalytics struct {
+	Name                string   `json:"name"`
+	Series              []Bucket `json:"series"`
+	CompletedTotal      int      `json:"completed_total"`
+	ThroughputPerPeriod float64  `json:"throughput_per_period"`
+	MedianLeadTimeHours *float64 `json:"median_lead_time_hours"`
+}
+
+// Analytics is the result of the task_analytics tool.
+type Analytics struct {
+	Start    string           `json:"start"`
+	End      string           `json:"end"`
+	Interval string           `json:"interval"`
+	GroupBy  string           `json:"group_by,omitempty"`
+	Groups   []GroupAnalytics `json:"groups"`
+}
+
+// taskTimes holds the parsed lifecycle dates of a task.
+type taskTimes struct {
+	task  Task
+	entry time.Time
+	end   time.Time
+	due   time.Time
+}
+
+func parseTimes(t Task) taskTimes {
+	tt := taskTimes{task: t}
+	tt.entry, _ = common.ParseDate(t.Entry)
+	tt.end, _ = common.ParseDate(t.End)
+	tt.due, _ = common.ParseDate(t.Due)
+	return tt
+}
+
+func (tt taskTimes) openAt(at time.Time) bool {
+	if tt.entry.IsZero() || tt.entry.After(at) {
+		return false
+	}
+	return tt.end.IsZero() || tt.end.After(at)
+}
+
+func inPeriod(t, start, end time.Time) bool {
+	return !t.IsZero() && !t.Before(start) && t.Before(end)
+}
+
+// periodStarts returns the start of every period between start and end,
+// plus the exclusive end of the last period.
+func periodStarts(start, end time.Time, interval string) []time.Time {
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	if interval == "week" {
+		offset := (int(start.Weekday()) + 6) % 7 // weeks start on Monday
+		start = start.AddDate(0, 0, -offset)
+	}
+	var bounds []time.Time
+	for t := start; !t.After(end); {
+		bounds = append(bounds, t)
+		if interval == "week" {
+			t = t.AddDate(0, 0, 7)
+		} else {
+			t = t.AddDate(0, 0, 1)
+		}
+		if t.After(end) {
+			bounds = append(bounds, t)
+		}
+	}
+	return bounds
+}
+
+func median(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sort.Float64s(values)
+	m := values[len(values)/2]
+	if len(values)%2 == 0 {
+		m = (values[len(values)/2-1] + m) / 2
+	}
+	m = math.Round(m*10) / 10
+	return &m
+}
+
+func analyseGroup(name string, tasks []taskTimes, bounds []time.Time) GroupAnalytics {
+	g := GroupAnalytics{Name: name, Series: []Bucket{}}
+	var leadTimes []float64
+	for i := 0; i+1 < len(bounds); i++ {
+		from, to := bounds[i], bounds[i+1]
+		b := Bucket{Start: from.Format("2006-01-02")}
+		for _, tt := range tasks {
+			if inPeriod(tt.entry, from, to) {
+				b.Created++
+			}
+			if inPeriod(tt.end, from, to) {
+				switch tt.task.Status {
+				case "completed":
+					b.Completed++
+					leadTimes = append(leadTimes, tt.end.Sub(tt.entry).Hours())
+				case "deleted":
+					b.Deleted++
+				}
+			}
+			if tt.openAt(to) {
+				b.Open++
+				if !tt.due.IsZero() && tt.due.Before(to) {
+					b.Overdue++
+				}
+			}
+		}
+		g.CompletedTotal += b.Completed
+		g.Series = append(g.Series, b)
+	}
+	if len(g.Series) > 0 {
+		g.ThroughputPerPeriod = math.Round(float64(g.CompletedTotal)/float64(len(g.Series))*100) / 100
+	}
+	g.MedianLeadTimeHours = median(leadTimes)
+	return g
+}
+
+// ComputeAnalytics builds per-group series between start and end (inclusive days).
+// groupBy is "project", "tag" or empty for a single group.
+func ComputeAnalytics(tasks []Task, start, end time.Time, interval, groupBy string) Analytics {
+	bounds := periodStarts(start, end, interval)
+	groups := map[string][]taskTimes{}
+	for _, t := range tasks {
+		if t.Status == "recurring" {
+			continue
+		}
+		tt := parseTimes(t)
+		switch groupBy {
+		case "project":
+			name := t.Project
+			if name == "" {
+				name = "(none)"
+			}
+			groups[name] = append(groups[name], tt)
+		case "tag":
+			if len(t.Tags) == 0 {
+				groups["(none)"] = append(groups["(none)"], tt)
+			}
+			for _, tag := range t.Tags {
+				groups[tag] = append(groups[tag], tt)
+			}
+		default:
+			groups["all"] = append(groups["all"], tt)
+		}
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	a := Analytics{
+		Start:    start.Format("2006-01-02"),
+		End:      end.Format("2006-01-02"),
+		Interval: interval,
+		GroupBy:  groupBy,
+		Groups:   []GroupAnalytics{},
+	}
+	for _, name := range names {
+		a.Groups = append(a.Groups, analyseGroup(name, groups[name], bounds))
+	}
+	return a
+}
+
+func analyticsHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	project, _ := argsMap["project"].(string)
+	tag, _ := argsMap["tag"].(string)
+	startArg, _ := argsMap["start"].(string)
+	endArg, _ := argsMap["end"].(string)
+	interval, _ := argsMap["interval"].(string)
+	groupBy, _ := argsMap["group_by"].(string)
+
+	if interval == "" {
+		interval = "day"
+	}
+	if interval != "day" && interval != "week" {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown interval %q", interval)), nil
+	}
+	loc := common.Location(ctx)
+	end := now().In(loc)
+	if endArg != "" {
+		t, err := common.ParseDay(endArg, loc)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, -29)
+	if interval == "week" {
+		start = end.AddDate(0, 0, -7*11)
+	}
+	if startArg != "" {
+		t, err := common.ParseDay(startArg, loc)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		start = t
+	}
+	if start.After(end) {
+		return mcp.NewToolResultError("start must not be after end"), nil
+	}
+
+	var filters []string
+	if project != "" {
+		filters = append(filters, "project:"+project)
+	}
+	if tag != "" {
+		filters = append(filters, "+"+tag)
+	}
+	// The active context would silently narrow the statistics to its tasks.
+	tasks, err := ExportAllTasks(ctx, filters...)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return common.JSONResult(ComputeAnalytics(tasks, start, end, interval, groupBy))
+}
@@ -0,0 +1,77 @@
+package taskwarrior
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// UDA describes a User Defined Attribute configured in taskrc.
+type UDA struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Label  string   `json:"label,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+// ShowConfig returns the effective Taskwarrior configuration as reported by `task _show`.
+func ShowConfig(ctx context.Context) (map[string]string, error) {
+	cmd := &TaskCommand{Command: "_show"}
+	out, err := cmd.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfig(out), nil
+}
+
+func parseConfig(out string) map[string]string {
+	cfg := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		key, val, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || key == "" {
+			continue
+		}
+		cfg[key] = val
+	}
+	return cfg
+}
+
+// ParseUDAs extracts the uda.* definitions from a configuration map, sorted by name.
+func ParseUDAs(cfg map[string]string) []UDA {
+	byName := map[string]*UDA{}
+	for key, val := range cfg {
+		if !strings.HasPrefix(key, "uda.") {
+			continue
+		}
+		rest := strings.TrimPrefix(key, "uda.")
+		dot := strings.LastIndex(rest, ".")
+		if dot <= 0 {
+			continue
+		}
+		name, field := rest[:dot], rest[dot+1:]
+		u, ok := byName[name]
+		if !ok {
+			u = &UDA{Name: name, Type: "string"}
+			byName[name] = u
+		}
+		switch field {
+		case "type":
+			u.Type = val
+		case "label":
+			u.Label = val
+		case "values":
+			for _, v := range strings.Split(val, ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					u.Values = append(u.Values, v)
+				}
+			}
+		}
+	}
+
+	udas := make([]UDA, 0, len(byName))
+	for _, u := range byName {
+		udas = append(udas, *u)
+	}
+	sort.Slice(udas, func(i, j int) bool { return udas[i].Name < udas[j].Name })
+	return udas
+}
@@ -0,0 +1,126 @@
+package taskwarrior
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// noContext is reported when no Taskwarrior context filters the output.
+const noContext = "none"
+
+// TaskContext is a named filter defined with `task context define`.
+type TaskContext struct {
+	Name        string `json:"name"`
+	ReadFilter  string `json:"read_filter"`
+	WriteFilter string `json:"write_filter,omitempty"`
+	Active      bool   `json:"active"`
+}
+
+// ParseContexts extracts context definitions from a configuration map.
+// Both the legacy `context.<name>` and the `context.<name>.read/.write` forms are understood.
+func ParseContexts(cfg map[string]string) []TaskContext {
+	byName := map[string]*TaskContext{}
+	get := func(name string) *TaskContext {
+		c, ok := byName[name]
+		if !ok {
+			c = &TaskContext{Name: name}
+			byName[name] = c
+		}
+		return c
+	}
+	for key, val := range cfg {
+		rest, ok := strings.CutPrefix(key, "context.")
+		if !ok || rest == "" {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(rest, ".read"):
+			get(strings.TrimSuffix(rest, ".read")).ReadFilter = val
+		case strings.HasSuffix(rest, ".write"):
+			get(strings.TrimSuffix(rest, ".write")).WriteFilter = val
+		case !strings.Contains(rest, "."):
+			c := get(rest)
+			if c.ReadFilter == "" {
+				c.ReadFilter = val
+			}
+		}
+	}
+
+	active := cfg["context"]
+	contexts := make([]TaskContext, 0, len(byName))
+	for _, c := range byName {
+		c.Active = c.Name == active
+		contexts = append(contexts, *c)
+	}
+	sort.Slice(contexts, func(i, j int) bool { return contexts[i].Name < contexts[j].Name })
+	return contexts
+}
+
+// activeContext returns the name of the currently selected context, or noContext.
+func activeContext(ctx context.Context) (string, error) {
+	cmd := &TaskCommand{
+		Command:       "_get",
+		Modifications: []string{"rc.context"},
+	}
+	out, err := cmd.Run(ctx)
+	if err != nil {
+		return "", err
+	}
+	if out = strings.TrimSpace(out); out == "" {
+		return noContext, nil
+	}
+	return out, nil
+}
+
+func contextListHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cfg, err := ShowConfig(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return common.JSONResult(ParseContexts(cfg))
+}
+
+func contextDefineHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	name, _ := argsMap["name"].(string)
+	filter, _ := argsMap["filter"].(string)
+	cmd := &TaskCommand{
+		Command:       "context",
+		Modifications: append([]string{"define", name}, strings.Fields(filter)...),
+	}
+	out, err := cmd.Run(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(out), nil
+}
+
+func contextSetHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	name, _ := argsMap["name"].(string)
+	cmd := &TaskCommand{
+		Command:       "context",
+		Modifications: []string{name},
+	}
+	out, err := cmd.Run(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(out), nil
+}
+
+func contextClearHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	cmd := &TaskCommand{
+		Command:       "context",
+		Modifications: []string{"none"},
+	}
+	out, err := cmd.Run(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(out), nil
+}
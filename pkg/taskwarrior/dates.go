@@ -0,0 +1,189 @@
+package taskwarrior
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+var phraseUnits = map[string]string{
+	"minute": "min", "min": "min", "hour": "h", "hr": "h", "day": "d",
+	"week": "w", "wk": "w", "month": "mo", "year": "y", "yr": "y",
+}
+
+var (
+	relativePhrase = regexp.MustCompile(`^(?:in\s+)?(\d+)\s*(minute|min|hour|hr|day|week|wk|month|year|yr)s?(\s+ago|\s+from\s+now)?$`)
+	atPhrase       = regexp.MustCompile(`^(.*?)\s*\bat\s+(.+)$`)
+	clockPhrase    = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?\s*(am|pm)?$`)
+	spacedDuration = regexp.MustCompile(`^(\d+)\s+([a-z]+)$`)
+	namedPeriods   = map[string]string{
+		"next week": "sonw", "next month": "sonm", "next year": "sony",
+		"end of week": "eow", "end of month": "eom", "end of year": "eoy",
+		"end of day": "eod", "start of week": "sow", "start of month": "som",
+		"this week": "sow", "this month": "som",
+	}
+)
+
+// parseClockPhrase turns "3pm", "3:30 pm", "15:00", "noon" or "midnight" into an offset from midnight.
+func parseClockPhrase(s string) (time.Duration, error) {
+	switch s {
+	case "noon", "midday":
+		return 12 * time.Hour, nil
+	case "midnight":
+		return 0, nil
+	}
+	m := clockPhrase.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("unrecognised time %q", s)
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute, _ := strconv.Atoi(m[2])
+	if m[3] != "" {
+		if hour < 1 || hour > 12 {
+			return 0, fmt.Errorf("unrecognised time %q", s)
+		}
+		hour %= 12
+		if m[3] == "pm" {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 {
+		return 0, fmt.Errorf("unrecognised time %q", s)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+// NormalizeDateExpr rewrites common English phrases into Taskwarrior date
+// expressions. Anything it does not recognise, such as Taskwarrior synonyms
+// (eow, som, monday) or ISO dates, is returned unchanged for `task calc`.
+func NormalizeDateExpr(phrase string) (string, error) {
+	p := strings.Join(strings.Fields(strings.ToLower(phrase)), " ")
+	if p == "" {
+		return "", fmt.Errorf("empty date expression")
+	}
+
+	if m := atPhrase.FindStringSubmatch(p); m != nil {
+		offset, err := parseClockPhrase(m[2])
+		if err != nil {
+			return "", err
+		}
+		base := m[1]
+		if base == "" {
+			base = "today"
+		}
+		if base, err = NormalizeDateExpr(base); err != nil {
+			return "", err
+		}
+		// Day expressions resolve to midnight; add the time of day to that.
+		if offset == 0 {
+			return base, nil
+		}
+		expr := base + "+" + strconv.Itoa(int(offset/time.Hour)) + "h"
+		if min := int((offset % time.Hour) / time.Minute); min > 0 {
+			expr += "+" + strconv.Itoa(min) + "min"
+		}
+		return expr, nil
+	}
+
+	if m := relativePhrase.FindStringSubmatch(p); m != nil && (strings.HasPrefix(p, "in ") || m[3] != "") {
+		sign := "+"
+		if strings.TrimSpace(m[3]) == "ago" {
+			sign = "-"
+		}
+		return "now" + sign + m[1] + phraseUnits[m[2]], nil
+	}
+	if named, ok := namedPeriods[p]; ok {
+		return named, nil
+	}
+	for _, prefix := range []string{"next ", "this ", "on "} {
+		if day, ok := strings.CutPrefix(p, prefix); ok {
+			if _, ok := weekdayNames[day]; ok {
+				return day, nil
+			}
+		}
+	}
+	if m := spacedDuration.FindStringSubmatch(p); m != nil {
+		return m[1] + m[2], nil
+	}
+	return strings.Join(strings.Fields(phrase), " "), nil
+}
+
+var weekdayNames = map[string]bool{
+	"monday": true, "tuesday": true, "wednesday": true, "thursday": true,
+	"friday": true, "saturday": true, "sunday": true,
+	"mon": true, "tue": true, "wed": true, "thu": true, "fri": true, "sat": true, "sun": true,
+}
+
+// ResolvedDate is a date expression resolved to an absolute time. ISO is in
+// the requested timezone; Taskwarrior is the same instant in the server's
+// zone, ready for the task and timew command lines.
+type ResolvedDate struct {
+	Input       string `json:"input"`
+	Expression  string `json:"expression"`
+	Timezone    string `json:"timezone"`
+	ISO         string `json:"iso"`
+	Taskwarrior string `json:"taskwarrior"`
+	UTC         string `json:"utc"`
+	Weekday     string `json:"weekday"`
+}
+
+// calcDate evaluates expr with `task calc` in loc, so that day boundaries such
+// as "tomorrow" or "eod" fall where the user expects, and parses the result.
+func calcDate(ctx context.Context, expr string, loc *time.Location) (time.Time, string, error) {
+	cmd := &TaskCommand{Command: "calc", Modifications: []string{expr}}
+	if loc != time.Local {
+		cmd.Env = []string{"TZ=" + loc.String()}
+	}
+	out, err := cmd.Run(ctx)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	out = strings.TrimSpace(out)
+	t, err := time.ParseInLocation(common.LocalLayout, out, loc)
+	return t, out, err
+}
+
+// ResolveDate resolves a phrase, a Taskwarrior synonym or a duration (taken
+// as relative to now, e.g. "2wks") to an absolute time in loc.
+func ResolveDate(ctx context.Context, phrase string, loc *time.Location) (ResolvedDate, error) {
+	expr, err := NormalizeDateExpr(phrase)
+	if err != nil {
+		return ResolvedDate{}, err
+	}
+	t, out, err := calcDate(ctx, expr, loc)
+	if err != nil && (strings.HasPrefix(out, "P") || strings.HasPrefix(out, "-P")) {
+		expr = "now+" + expr
+		t, out, err = calcDate(ctx, expr, loc)
+	}
+	if err != nil {
+		if out != "" {
+			return ResolvedDate{}, fmt.Errorf("%q does not resolve to a date (task calc gave %q)", phrase, out)
+		}
+		return ResolvedDate{}, err
+	}
+	return ResolvedDate{
+		Input:       phrase,
+		Expression:  expr,
+		Timezone:    loc.String(),
+		ISO:         t.Format(time.RFC3339),
+		Taskwarrior: common.CommandDate(t),
+		UTC:         t.UTC().Format(dateLayout),
+		Weekday:     t.Weekday().String(),
+	}, nil
+}
+
+func resolveDateHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	phrase, _ := argsMap["expression"].(string)
+	resolved, err := ResolveDate(ctx, phrase, common.Location(ctx))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return common.JSONResult(resolved)
+}
@@ -0,0 +1,391 @@
+package taskwarrior
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// GraphNode is a task in the dependency graph.
+type GraphNode struct {
+	UUID        string `json:"uuid"`
+	ID          int    `json:"id,omitempty"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+	Project     string `json:"project,omitempty"`
+}
+
+// GraphEdge points from a task to a task it depends on.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Unblocker counts how many open tasks transitively wait on a task.
+type Unblocker struct {
+	UUID        string `json:"uuid"`
+	Description string `json:"description"`
+	Unblocks    int    `json:"unblocks"`
+}
+
+// DependencyGraph is the analysed dependency DAG of a set of tasks.
+type DependencyGraph struct {
+	Nodes        []GraphNode `json:"nodes"`
+	Edges        []GraphEdge `json:"edges"`
+	Blocked      []string    `json:"blocked"`
+	UnblocksMost []Unblocker `json:"unblocks_most"`
+	CriticalPath []string    `json:"critical_path"`
+	Cycles       [][]string  `json:"cycles,omitempty"`
+	Diagram      string      `json:"diagram,omitempty"`
+}
+
+func isOpen(t Task) bool {
+	return t.Status == "pending" || t.Status == "waiting"
+}
+
+// exportOpen returns every task that is neither completed nor deleted.
+func exportOpen(ctx context.Context) ([]Task, error) {
+	return ExportTasks(ctx, "status.not:completed", "status.not:deleted")
+}
+
+// findCycle returns a dependency path from start back to start, if any.
+func findCycle(depends map[string][]string, start string) []string {
+	visited := map[string]bool{}
+	var path []string
+	var walk func(id string) bool
+	walk = func(id string) bool {
+		path = append(path, id)
+		for _, dep := range depends[id] {
+			if dep == start {
+				path = append(path, dep)
+				return true
+			}
+			if !visited[dep] {
+				visited[dep] = true
+				if walk(dep) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if walk(start) {
+		return path
+	}
+	return nil
+}
+
+// BuildGraph analyses the dependencies among tasks. open is the set of
+// unfinished tasks used to decide whether a dependency still blocks.
+func BuildGraph(tasks []Task, open []Task) DependencyGraph {
+	g := DependencyGraph{
+		Nodes:        []GraphNode{},
+		Edges:        []GraphEdge{},
+		Blocked:      []string{},
+		UnblocksMost: []Unblocker{},
+		CriticalPath: []string{},
+	}
+	inSet := map[string]Task{}
+	for _, t := range tasks {
+		inSet[t.UUID] = t
+	}
+	openSet := map[string]bool{}
+	for _, t := range open {
+		openSet[t.UUID] = true
+	}
+
+	depends := map[string][]string{}
+	dependents := map[string][]string{}
+	for _, t := range tasks {
+		g.Nodes = append(g.Nodes, GraphNode{UUID: t.UUID, ID: t.ID, Description: t.Description, Status: t.Status, Project: t.Project})
+		blocked := false
+		for _, dep := range t.Depends {
+			if openSet[dep] && isOpen(t) {
+				blocked = true
+			}
+			if _, ok := inSet[dep]; !ok {
+				continue
+			}
+			g.Edges = append(g.Edges, GraphEdge{From: t.UUID, To: dep})
+			depends[t.UUID] = append(depends[t.UUID], dep)
+			dependents[dep] = append(dependents[dep], t.UUID)
+		}
+		if blocked {
+			g.Blocked = append(g.Blocked, t.UUID)
+		}
+	}
+
+	seenCycle := map[string]bool{}
+	for _, t := range tasks {
+		if seenCycle[t.UUID] {
+			continue
+		}
+		if cycle := findCycle(depends, t.UUID); cycle != nil {
+			for _, id := range cycle {
+				seenCycle[id] = true
+			}
+			g.Cycles = append(g.Cycles, cycle)
+		}
+	}
+
+	for _, t := range tasks {
+		if !isOpen(t) {
+			continue
+		}
+		reached := map[string]bool{}
+		stack := append([]string{}, dependents[t.UUID]...)
+		for len(stack) > 0 {
+			id := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if reached[id] || id == t.UUID {
+				continue
+			}
+			reached[id] = true
+			stack = append(stack, dependents[id]...)
+		}
+		count := 0
+		for id := range reached {
+			if isOpen(inSet[id]) {
+				count++
+			}
+		}
+		if count > 0 {
+			g.UnblocksMost = append(g.UnblocksMost, Unblocker{UUID: t.UUID, Description: t.Description, Unblocks: count})
+		}
+	}
+	sort.SliceStable(g.UnblocksMost, func(i, j int) bool {
+		return g.UnblocksMost[i].Unblocks > g.UnblocksMost[j].Unblocks
+	})
+
+	if len(g.Cycles) == 0 {
+		g.CriticalPath = criticalPath(tasks, depends, inSet)
+	}
+	return g
+}
+
+// criticalPath returns the longest chain of open tasks in execution order,
+// i.e. the first task to do comes first.
+func criticalPath(tasks []Task, depends map[string][]string, inSet map[string]Task) []string {
+	memo := map[string][]string{}
+	var longest func(id string) []string
+	longest = func(id string) []string {
+		if p, ok := memo[id]; ok {
+			return p
+		}
+		var best []string
+		for _, dep := range depends[id] {
+			if !isOpen(inSet[dep]) {
+				continue
+			}
+			if p := longest(dep); len(p) > len(best) {
+				best = p
+			}
+		}
+		path := append(append([]string{}, best...), id)
+		memo[id] = path
+		return path
+	}
+
+	var best []string
+	for _, t := range tasks {
+		if !isOpen(t) {
+			continue
+		}
+		if p := longest(t.UUID); len(p) > len(best) {
+			best = p
+		}
+	}
+	if len(best) < 2 {
+		return []string{}
+	}
+	return best
+}
+
+// nodeID names a task in Mermaid and DOT output. It uses the whole UUID, as
+// tasks sharing a short UUID prefix would otherwise merge into one node.
+func nodeID(uuid string) string {
+	return "t" + strings.ReplaceAll(uuid, "-", "")
+}
+
+func nodeLabel(n GraphNode) string {
+	label := n.Description
+	if n.ID > 0 {
+		label = fmt.Sprintf("%d: %s", n.ID, label)
+	}
+	return strings.NewReplacer(`"`, "'", "\n", " ").Replace(label)
+}
+
+// Mermaid renders the graph as a Mermaid flowchart; arrows point from a
+// dependency to the task it unblocks.
+func (g DependencyGraph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("graph TD\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", nodeID(n.UUID), nodeLabel(n))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s --> %s\n", nodeID(e.To), nodeID(e.From))
+	}
+	for _, id := range g.Blocked {
+		fmt.Fprintf(&b, "  class %s blocked\n", nodeID(id))
+	}
+	if len(g.Blocked) > 0 {
+		b.WriteString("  classDef blocked stroke:#c00\n")
+	}
+	return b.String()
+}
+
+// DOT renders the graph in Graphviz DOT format.
+func (g DependencyGraph) DOT() string {
+	blocked := map[string]bool{}
+	for _, id := range g.Blocked {
+		blocked[id] = true
+	}
+	var b strings.Builder
+	b.WriteString("digraph tasks {\n  rankdir=LR;\n")
+	for _, n := range g.Nodes {
+		attrs := fmt.Sprintf("label=\"%s\"", nodeLabel(n))
+		if blocked[n.UUID] {
+			attrs += ", color=red"
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", nodeID(n.UUID), attrs)
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s;\n", nodeID(e.To), nodeID(e.From))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func dependencyArgs(argsMap map[string]any) (string, []string, error) {
+	uuid, _ := argsMap["uuid"].(string)
+	deps := stringSlice(argsMap["depends"])
+	if uuid == "" || len(deps) == 0 {
+		return "", nil, fmt.Errorf("uuid and depends are required")
+	}
+	return uuid, deps, nil
+}
+
+// resolveTask finds the UUID of the one task ref names, by ID, UUID or UUID prefix.
+func resolveTask(tasks []Task, ref string) (string, error) {
+	id, err := strconv.Atoi(ref)
+	isID := err == nil && id > 0
+	var matches []string
+	for _, t := range tasks {
+		if (isID && t.ID == id) || (!isID && strings.HasPrefix(t.UUID, ref)) {
+			matches = append(matches, t.UUID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no task matches %q", ref)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("%q matches %d tasks: use a longer UUID", ref, len(matches))
+}
+
+func dependAddHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	uuid, deps, err := dependencyArgs(argsMap)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	tasks, err := ExportAllTasks(ctx, "status.not:deleted")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	// IDs and short UUIDs are resolved first, so the cycle check sees the
+	// same tasks Taskwarrior would modify.
+	if uuid, err = resolveTask(tasks, uuid); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	for i, dep := range deps {
+		if deps[i], err = resolveTask(tasks, dep); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+	depends := map[string][]string{}
+	for _, t := range tasks {
+		depends[t.UUID] = t.Depends
+	}
+	for _, dep := range deps {
+		if dep == uuid {
+			return mcp.NewToolResultError("a task cannot depend on itself"), nil
+		}
+		depends[uuid] = append(depends[uuid], dep)
+	}
+	if cycle := findCycle(depends, uuid); cycle != nil {
+		return mcp.NewToolResultError("adding this dependency would create a cycle: " + strings.Join(cycle, " -> ")), nil
+	}
+
+	cmd := &TaskCommand{
+		Filters:       []string{uuid},
+		Command:       "modify",
+		Modifications: []string{"depends:" + strings.Join(deps, ",")},
+	}
+	out, err := cmd.Run(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(out), nil
+}
+
+func dependRemoveHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	uuid, deps, err := dependencyArgs(argsMap)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	removals := make([]string, len(deps))
+	for i, dep := range deps {
+		removals[i] = "-" + dep
+	}
+	cmd := &TaskCommand{
+		Filters:       []string{uuid},
+		Command:       "modify",
+		Modifications: []string{"depends:" + strings.Join(removals, ",")},
+	}
+	out, err := cmd.Run(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(out), nil
+}
+
+func graphHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	filter, _ := argsMap["filter"].(string)
+	format, _ := argsMap["format"].(string)
+	if filter == "" {
+		filter = common.CurrentConfig().Task.DefaultFilter
+	}
+
+	tasks, err := ExportTasks(ctx, strings.Fields(filter)...)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	open, err := exportOpen(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	g := BuildGraph(tasks, open)
+	switch format {
+	case "", "mermaid":
+		g.Diagram = g.Mermaid()
+	case "dot":
+		g.Diagram = g.DOT()
+	case "json":
+	default:
+		return mcp.NewToolResultError(fmt.Sprintf("unknown format %q", format)), nil
+	}
+	return common.LocalJSONResult(ctx, g)
+}
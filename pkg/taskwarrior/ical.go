@@ -0,0 +1,270 @@
+package taskwarrior
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// defaultCalendarFilter selects the tasks published on task://calendar.ics.
+const defaultCalendarFilter = "status:pending due.any:"
+
+var icalPriority = map[string]string{"H": "1", "M": "5", "L": "9"}
+
+var icalStatus = map[string]string{
+	"pending":   "NEEDS-ACTION",
+	"waiting":   "NEEDS-ACTION",
+	"recurring": "NEEDS-ACTION",
+	"completed": "COMPLETED",
+	"deleted":   "CANCELLED",
+}
+
+// VTODO converts a task into an RFC 5545 VTODO component.
+func VTODO(t Task) common.ICalComponent {
+	c := common.ICalComponent{Name: "VTODO"}
+	c.Add("UID", t.UUID)
+	c.Add("DTSTAMP", t.Modified)
+	c.Add("CREATED", t.Entry)
+	c.Add("LAST-MODIFIED", t.Modified)
+	c.AddText("SUMMARY", t.Description)
+
+	status := icalStatus[t.Status]
+	if t.Status == "pending" && t.Start != "" {
+		status = "IN-PROCESS"
+	}
+	c.Add("STATUS", status)
+	if t.Status == "completed" {
+		c.Add("COMPLETED", t.End)
+	}
+	// RFC 5545 requires DUE to be later than DTSTART, and Taskwarrior allows
+	// scheduling a task after it is due: keep the due date in that case.
+	scheduled, errS := common.ParseDate(t.Scheduled)
+	due, errD := common.ParseDate(t.Due)
+	if errS != nil || errD != nil || scheduled.Before(due) {
+		c.Add("DTSTART", t.Scheduled)
+	}
+	c.Add("DUE", t.Due)
+	c.Add("PRIORITY", icalPriority[t.Priority])
+
+	if len(t.Tags) > 0 {
+		escaped := make([]string, len(t.Tags))
+		for i, tag := range t.Tags {
+			escaped[i] = common.ICalEscape(tag)
+		}
+		c.Add("CATEGORIES", strings.Join(escaped, ","))
+	}
+	if t.Project != "" {
+		c.Properties = append(c.Properties, common.ICalProperty{Name: "X-TASKWARRIOR-PROJECT", Value: common.ICalEscape(t.Project)})
+	}
+	if len(t.Annotations) > 0 {
+		notes := make([]string, len(t.Annotations))
+		for i, a := range t.Annotations {
+			notes[i] = a.Description
+		}
+		c.AddText("DESCRIPTION", strings.Join(notes, "\n"))
+	}
+	for _, dep := range t.Depends {
+		c.Properties = append(c.Properties, common.ICalProperty{Name: "RELATED-TO", Params: "RELTYPE=DEPENDS-ON", Value: dep})
+	}
+	return c
+}
+
+// TasksToICal renders tasks as a VCALENDAR of VTODOs.
+func TasksToICal(tasks []Task) string {
+	components := make([]common.ICalComponent, len(tasks))
+	for i, t := range tasks {
+		components[i] = VTODO(t)
+	}
+	return common.WriteCalendar(components)
+}
+
+func exportICalHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	filter, _ := argsMap["filter"].(string)
+	if filter == "" {
+		filter = defaultCalendarFilter
+	}
+	tasks, err := ExportTasks(ctx, strings.Fields(filter)...)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(TasksToICal(tasks)), nil
+}
+
+func calendarResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	filter := defaultCalendarFilter
+	if u, err := url.Parse(req.Params.URI); err == nil {
+		if f := u.Query().Get("filter"); f != "" {
+			filter = f
+		}
+	}
+	tasks, err := ExportTasks(ctx, strings.Fields(filter)...)
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      req.Params.URI,
+			MIMEType: "text/calendar",
+			Text:     TasksToICal(tasks),
+		},
+	}, nil
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// icalDate converts a date property into Taskwarrior's export format. Floating
+// times and dates without a TZID are read in loc.
+func icalDate(c common.ICalComponent, name string, loc *time.Location) (string, error) {
+	p, ok := c.Get(name)
+	if !ok || p.Value == "" {
+		return "", nil
+	}
+	t, err := p.Time(loc)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s %q: %v", name, p.Value, err)
+	}
+	return t.UTC().Format(dateLayout), nil
+}
+
+// TaskFromVTODO maps a VTODO onto a Task, reading floating dates in loc. The
+// task UUID is the UID itself when it is a UUID, and otherwise derived from
+// it, which makes re-imports idempotent.
+func TaskFromVTODO(c common.ICalComponent, loc *time.Location) (Task, error) {
+	uid, _ := c.Get("UID")
+	summary, _ := c.Get("SUMMARY")
+	if uid.Value == "" {
+		return Task{}, fmt.Errorf("VTODO without UID")
+	}
+	if summary.Value == "" {
+		return Task{}, fmt.Errorf("VTODO %s without SUMMARY", uid.Value)
+	}
+
+	t := Task{
+		UUID:        strings.ToLower(uid.Value),
+		Description: common.ICalUnescape(summary.Value),
+		Status:      "pending",
+	}
+	if !uuidPattern.MatchString(uid.Value) {
+		t.UUID = StableUUID(uid.Value)
+	}
+
+	var err error
+	if t.Due, err = icalDate(c, "DUE", loc); err != nil {
+		return Task{}, err
+	}
+	if t.Scheduled, err = icalDate(c, "DTSTART", loc); err != nil {
+		return Task{}, err
+	}
+	if t.Entry, err = icalDate(c, "CREATED", loc); err != nil {
+		return Task{}, err
+	}
+
+	if p, ok := c.Get("PRIORITY"); ok {
+		switch n, _ := strconv.Atoi(p.Value); {
+		case n >= 1 && n <= 4:
+			t.Priority = "H"
+		case n == 5:
+			t.Priority = "M"
+		case n >= 6 && n <= 9:
+			t.Priority = "L"
+		}
+	}
+
+	for _, p := range c.Properties {
+		if p.Name != "CATEGORIES" {
+			continue
+		}
+		for _, cat := range splitICalList(p.Value) {
+			if tag := strings.Join(strings.Fields(cat), "_"); tag != "" {
+				t.Tags = append(t.Tags, tag)
+			}
+		}
+	}
+
+	if p, ok := c.Get("DESCRIPTION"); ok && p.Value != "" {
+		entry := t.Entry
+		if entry == "" {
+			entry = now().UTC().Format(dateLayout)
+		}
+		t.Annotations = []Annotation{{Entry: entry, Description: common.ICalUnescape(p.Value)}}
+	}
+
+	status, _ := c.Get("STATUS")
+	switch strings.ToUpper(status.Value) {
+	case "COMPLETED":
+		t.Status = "completed"
+		if t.End, err = icalDate(c, "COMPLETED", loc); err != nil {
+			return Task{}, err
+		}
+		if t.End == "" {
+			t.End = now().UTC().Format(dateLayout)
+		}
+	case "CANCELLED":
+		t.Status = "deleted"
+		t.End = now().UTC().Format(dateLayout)
+	}
+	return t, nil
+}
+
+// splitICalList splits a comma separated value, respecting escaped commas.
+func splitICalList(value string) []string {
+	var items []string
+	var cur strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value):
+			cur.WriteByte(value[i])
+			cur.WriteByte(value[i+1])
+			i++
+		case value[i] == ',':
+			items = append(items, common.ICalUnescape(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteByte(value[i])
+		}
+	}
+	return append(items, common.ICalUnescape(cur.String()))
+}
+
+func importICalHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	data, _ := argsMap["ics_data"].(string)
+	dryRun, _ := argsMap["dry_run"].(bool)
+
+	components, err := common.ParseCalendar(data)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	var tasks []Task
+	var sources []string
+	for _, c := range components {
+		if c.Name != "VTODO" {
+			continue
+		}
+		t, err := TaskFromVTODO(c, common.Location(ctx))
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		uid, _ := c.Get("UID")
+		tasks = append(tasks, t)
+		sources = append(sources, uid.Value)
+	}
+	if len(tasks) == 0 {
+		return mcp.NewToolResultError("no VTODO components found"), nil
+	}
+
+	// The item carries only some attributes: keep the others the task has.
+	report, err := runImport(ctx, tasks, sources, true, dryRun)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return common.JSONResult(report)
+}
@@ -0,0 +1,263 @@
+package taskwarrior
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+)
+
+// FieldChange is a single attribute that differs between an existing task and
+// the version about to be imported.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// diffIgnored lists attributes Taskwarrior maintains itself.
+var diffIgnored = map[string]bool{"id": true, "urgency": true, "modified": true, "entry": true}
+
+func taskMap(t Task) map[string]any {
+	data, _ := json.Marshal(t)
+	var m map[string]any
+	json.Unmarshal(data, &m)
+	return m
+}
+
+// DiffTasks reports the attributes whose value differs between existing and
+// incoming, including those incoming leaves out, as replacing existing with
+// incoming would change them.
+func DiffTasks(existing, incoming Task) []FieldChange {
+	oldMap, newMap := taskMap(existing), taskMap(incoming)
+	var keys []string
+	for k := range oldMap {
+		if _, ok := newMap[k]; !ok && !diffIgnored[k] {
+			keys = append(keys, k)
+		}
+	}
+	for k := range newMap {
+		if !diffIgnored[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := []FieldChange{}
+	for _, k := range keys {
+		if !reflect.DeepEqual(oldMap[k], newMap[k]) {
+			changes = append(changes, FieldChange{Field: k, Old: oldMap[k], New: newMap[k]})
+		}
+	}
+	return changes
+}
+
+// MergeTask overlays the attributes set on incoming onto existing, so that an
+// import does not drop attributes the source does not know about.
+func MergeTask(existing, incoming Task) Task {
+	merged := taskMap(existing)
+	for k, v := range taskMap(incoming) {
+		if k == "annotations" {
+			continue
+		}
+		merged[k] = v
+	}
+	data, _ := json.Marshal(merged)
+	var t Task
+	json.Unmarshal(data, &t)
+
+	// Keep the original completion time while the status is unchanged.
+	if existing.Status == incoming.Status && existing.End != "" {
+		t.End = existing.End
+	}
+
+	t.Annotations = existing.Annotations
+	for _, a := range incoming.Annotations {
+		found := false
+		for _, e := range existing.Annotations {
+			if e.Description == a.Description {
+				found = true
+			}
+		}
+		if !found {
+			t.Annotations = append(t.Annotations, a)
+		}
+	}
+	return t
+}
+
+// uuidNamespace is the RFC 4122 URL namespace, used to derive stable task
+// UUIDs from foreign identifiers.
+var uuidNamespace = [16]byte{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// StableUUID derives a version 5 UUID from name, so that re-importing the same
+// foreign item always targets the same task.
+func StableUUID(name string) string {
+	h := sha1.New()
+	h.Write(uuidNamespace[:])
+	h.Write([]byte(name))
+	sum := h.Sum(nil)
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// existingTasks exports the tasks with the given UUIDs, keyed by UUID, also
+// when the active context hides them. Empty UUIDs are ignored.
+func existingTasks(ctx context.Context, uuids []string) (map[string]Task, error) {
+	existing := map[string]Task{}
+	var filter []string
+	for _, uuid := range uuids {
+		if uuid != "" {
+			filter = append(filter, uuid)
+		}
+	}
+	if len(filter) == 0 {
+		return existing, nil
+	}
+	tasks, err := ExportAllTasks(ctx, filter...)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tasks {
+		existing[t.UUID] = t
+	}
+	return existing, nil
+}
+
+// importJSON feeds a JSON task array to `task import` via a temporary file.
+func importJSON(ctx context.Context, data string) (string, error) {
+	tmpFile, err := os.CreateTemp("", "task_import_*.json")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.WriteString(data); err != nil {
+		return "", err
+	}
+	tmpFile.Close()
+
+	cmd := &TaskCommand{
+		Command:       "import",
+		Modifications: []string{tmpFile.Name()},
+	}
+	return cmd.Run(ctx)
+}
+
+// importTasks imports typed tasks.
+func importTasks(ctx context.Context, tasks []Task) (string, error) {
+	data, err := json.Marshal(tasks)
+	if err != nil {
+		return "", err
+	}
+	return importJSON(ctx, string(data))
+}
+
+// ImportItem describes what an import did, or would do, with one incoming item.
+type ImportItem struct {
+	Source      string        `json:"source,omitempty"`
+	UUID        string        `json:"uuid"`
+	Description string        `json:"description"`
+	Action      string        `json:"action"`
+	Changes     []FieldChange `json:"changes,omitempty"`
+}
+
+// ImportReport summarises an import.
+type ImportReport struct {
+	DryRun  bool         `json:"dry_run"`
+	Created int          `json:"created"`
+	Updated int          `json:"updated"`
+	Skipped int          `json:"skipped"`
+	Items   []ImportItem `json:"items"`
+	Output  string       `json:"output,omitempty"`
+}
+
+// planImport compares incoming tasks with the database and returns the tasks
+// that need importing along with a report. sources labels each incoming task.
+// Like `task import`, an incoming task replaces the existing one with its UUID,
+// unless merge is set: then it is merged into it with MergeTask.
+func planImport(ctx context.Context, incoming []Task, sources []string, merge, dryRun bool) ([]Task, ImportReport, error) {
+	uuids := make([]string, len(incoming))
+	for i, t := range incoming {
+		uuids[i] = t.UUID
+	}
+	existing, err := existingTasks(ctx, uuids)
+	if err != nil {
+		return nil, ImportReport{}, err
+	}
+
+	report := ImportReport{DryRun: dryRun, Items: []ImportItem{}}
+	var toImport []Task
+	for i, t := range incoming {
+		item := ImportItem{UUID: t.UUID, Description: t.Description}
+		if i < len(sources) {
+			item.Source = sources[i]
+		}
+		if old, ok := existing[t.UUID]; ok {
+			if merge {
+				t = MergeTask(old, t)
+			}
+			if changes := DiffTasks(old, t); len(changes) > 0 {
+				item.Action = "update"
+				item.Changes = changes
+				report.Updated++
+				toImport = append(toImport, t)
+			} else {
+				item.Action = "skip"
+				report.Skipped++
+			}
+		} else {
+			item.Action = "create"
+			report.Created++
+			toImport = append(toImport, t)
+		}
+		report.Items = append(report.Items, item)
+	}
+	return toImport, report, nil
+}
+
+// runImport plans and, unless dryRun is set, performs the import.
+func runImport(ctx context.Context, incoming []Task, sources []string, merge, dryRun bool) (ImportReport, error) {
+	toImport, report, err := planImport(ctx, incoming, sources, merge, dryRun)
+	if err != nil || dryRun || len(toImport) == 0 {
+		return report, err
+	}
+	out, err := importTasks(ctx, toImport)
+	if err != nil {
+		return report, err
+	}
+	report.Output = out
+	return report, nil
+}
+
+// replaceImport plans the import of objects, decoded and validated as tasks,
+// and unless dryRun is set feeds the objects that change something to
+// `task import` unchanged.
+func replaceImport(ctx context.Context, objects []map[string]any, tasks []Task, dryRun bool) (ImportReport, error) {
+	_, report, err := planImport(ctx, tasks, nil, false, dryRun)
+	if err != nil || dryRun {
+		return report, err
+	}
+	var changed []map[string]any
+	for i, item := range report.Items {
+		if item.Action != "skip" {
+			changed = append(changed, objects[i])
+		}
+	}
+	if len(changed) == 0 {
+		return report, nil
+	}
+	data, err := json.Marshal(changed)
+	if err != nil {
+		return report, err
+	}
+	out, err := importJSON(ctx, string(data))
+	if err != nil {
+		return report, err
+	}
+	report.Output = out
+	return report, nil
+}
@@ -0,0 +1,203 @@
+package taskwarrior
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// namedRecurPeriods are the period names Taskwarrior accepts for recur.
+var namedRecurPeriods = map[string]bool{
+	"daily": true, "day": true, "weekdays": true, "weekly": true,
+	"biweekly": true, "fortnight": true, "monthly": true, "bimonthly": true,
+	"quarterly": true, "semiannual": true, "annual": true, "yearly": true,
+	"biannual": true, "biyearly": true,
+}
+
+var (
+	numberedRecurPeriod = regexp.MustCompile(`^\d*\s*(d|days?|w|wks?|weeks?|m|mos?|months?|q|qtrs?|quarters?|y|yrs?|years?|h|hrs?|hours?|min|mins|minutes?)$`)
+	isoRecurPeriod      = regexp.MustCompile(`^P(\d+Y)?(\d+M)?(\d+W)?(\d+D)?(T(\d+H)?(\d+M)?(\d+S)?)?$`)
+)
+
+// ValidRecurPeriod reports whether period is a recurrence Taskwarrior understands.
+func ValidRecurPeriod(period string) bool {
+	p := strings.ToLower(strings.TrimSpace(period))
+	if p == "" || p == "p" || p == "pt" {
+		return false
+	}
+	return namedRecurPeriods[p] || numberedRecurPeriod.MatchString(p) || isoRecurPeriod.MatchString(strings.ToUpper(p))
+}
+
+// validateDateExpr checks that Taskwarrior resolves expr to a date.
+func validateDateExpr(ctx context.Context, expr string) error {
+	cmd := &TaskCommand{
+		Command:       "calc",
+		Modifications: []string{expr},
+	}
+	out, err := cmd.Run(ctx)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(out, "T") || strings.HasPrefix(out, "P") {
+		return fmt.Errorf("%q does not resolve to a date (got %q)", expr, out)
+	}
+	return nil
+}
+
+// Recurrence is a recurrence template together with its pending instances.
+type Recurrence struct {
+	Template  Task   `json:"template"`
+	Instances []Task `json:"instances"`
+}
+
+// GroupRecurrences pairs templates with the open tasks generated from them.
+func GroupRecurrences(templates []Task, open []Task) []Recurrence {
+	children := map[string][]Task{}
+	for _, t := range open {
+		if t.Parent != "" {
+			children[t.Parent] = append(children[t.Parent], t)
+		}
+	}
+	recurrences := make([]Recurrence, 0, len(templates))
+	for _, tmpl := range templates {
+		instances := children[tmpl.UUID]
+		sort.SliceStable(instances, func(i, j int) bool { return instances[i].Due < instances[j].Due })
+		if instances == nil {
+			instances = []Task{}
+		}
+		recurrences = append(recurrences, Recurrence{Template: tmpl, Instances: instances})
+	}
+	return recurrences
+}
+
+func recurAddHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	desc, _ := argsMap["description"].(string)
+	recur, _ := argsMap["recur"].(string)
+	until, _ := argsMap["until"].(string)
+	due, _ := argsMap["due"].(string)
+	meta, _ := argsMap["metadata"].(string)
+
+	if !ValidRecurPeriod(recur) {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid recur period %q (e.g. daily, weekly, 2wks, P1M)", recur)), nil
+	}
+	if due == "" {
+		return mcp.NewToolResultError("recurring tasks require a due date"), nil
+	}
+	mods := []string{desc, "recur:" + recur}
+	if until != "" {
+		if err := validateDateExpr(ctx, until); err != nil {
+			return mcp.NewToolResultError("invalid until: " + err.Error()), nil
+		}
+		mods = append(mods, "until:"+until)
+	}
+	mods = append(mods, attributeArgs(argsMap)...)
+	mods = append(mods, strings.Fields(meta)...)
+
+	task, err := addTask(ctx, mods)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return common.LocalJSONResult(ctx, task)
+}
+
+func recurListHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	filter, _ := argsMap["filter"].(string)
+
+	templates, err := ExportTasks(ctx, append([]string{"status:recurring"}, strings.Fields(filter)...)...)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	open, err := exportOpen(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return common.LocalJSONResult(ctx, GroupRecurrences(templates, open))
+}
+
+func recurModifyHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	uuid, _ := argsMap["uuid"].(string)
+	mods, _ := argsMap["modifications"].(string)
+	propagate, _ := argsMap["propagate"].(bool)
+
+	if recur, ok := argsMap["recur"].(string); ok && recur != "" {
+		if !ValidRecurPeriod(recur) {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid recur period %q", recur)), nil
+		}
+		mods += " recur:" + recur
+	}
+	modifications := append(strings.Fields(mods), attributeArgs(map[string]any{"udas": argsMap["udas"]})...)
+	if len(modifications) == 0 {
+		return mcp.NewToolResultError("modifications, recur or udas is required"), nil
+	}
+
+	// Recurrence propagation is handled explicitly below, so never let
+	// Taskwarrior apply it implicitly.
+	cmd := &TaskCommand{
+		Overrides:     []string{"rc.recurrence.confirmation=no"},
+		Filters:       []string{uuid},
+		Command:       "modify",
+		Modifications: modifications,
+	}
+	out, err := cmd.Run(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if propagate {
+		children := &TaskCommand{
+			Overrides:     []string{"rc.recurrence.confirmation=no"},
+			Filters:       openInstances(uuid),
+			Command:       "modify",
+			Modifications: modifications,
+		}
+		childOut, err := children.Run(ctx)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		out = strings.TrimSpace(out + "\n" + childOut)
+	}
+	return mcp.NewToolResultText(out), nil
+}
+
+// openInstances filters the instances of a recurrence template that are not
+// done yet. Waiting instances are hidden from status:pending but still open.
+func openInstances(uuid string) []string {
+	return []string{"parent:" + uuid, "(", "status:pending", "or", "status:waiting", ")"}
+}
+
+func recurStopHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	uuid, _ := argsMap["uuid"].(string)
+	deletePending, _ := argsMap["delete_pending"].(bool)
+
+	// Deleting the template stops new instances from being generated.
+	cmd := &TaskCommand{
+		Overrides: []string{"rc.recurrence.confirmation=no"},
+		Filters:   []string{uuid},
+		Command:   "delete",
+	}
+	out, err := cmd.Run(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if deletePending {
+		children := &TaskCommand{
+			Overrides: []string{"rc.recurrence.confirmation=no"},
+			Filters:   openInstances(uuid),
+			Command:   "delete",
+		}
+		childOut, err := children.Run(ctx)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		out = strings.TrimSpace(out + "\n" + childOut)
+	}
+	return mcp.NewToolResultText(out), nil
+}
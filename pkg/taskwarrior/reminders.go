@@ -0,0 +1,204 @@
+package taskwarrior
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// ReminderConfig sets how long before a due or scheduled date a reminder
+// fires, and how often the scheduler checks.
+type ReminderConfig struct {
+	Enabled   bool
+	Due       time.Duration
+	Scheduled time.Duration
+	Interval  time.Duration
+}
+
+// CurrentReminderConfig returns the reminder settings of the warmcp config file.
+func CurrentReminderConfig() ReminderConfig {
+	cfg := common.CurrentConfig()
+	return ReminderConfig{
+		Enabled:   cfg.Features.Reminders,
+		Due:       time.Duration(cfg.Reminders.Due),
+		Scheduled: time.Duration(cfg.Reminders.Scheduled),
+		Interval:  time.Duration(cfg.Reminders.Interval),
+	}
+}
+
+// Reminder is one notification about a task.
+type Reminder struct {
+	Event       string `json:"event"`
+	UUID        string `json:"uuid"`
+	Description string `json:"description"`
+	Date        string `json:"date"`
+	Message     string `json:"message"`
+}
+
+// key identifies a reminder, so a task is notified again only when the date changes.
+func (r Reminder) key() string {
+	return r.Event + "|" + r.UUID + "|" + r.Date
+}
+
+// DueReminders evaluates pending tasks at time t, rendering dates in loc.
+// Scheduled and wait dates only produce a reminder when their lead time was
+// reached after since, so old dates are not reported again on every check.
+func DueReminders(tasks []Task, rc ReminderConfig, t, since time.Time, loc *time.Location) []Reminder {
+	var reminders []Reminder
+	add := func(event string, task Task, date time.Time, format string) {
+		reminders = append(reminders, Reminder{
+			Event:       event,
+			UUID:        task.UUID,
+			Description: task.Description,
+			Date:        date.In(loc).Format(time.RFC3339),
+			Message:     fmt.Sprintf(format, task.Description, date.In(loc).Format("Mon 2006-01-02 15:04")),
+		})
+	}
+	for _, task := range tasks {
+		if task.Status != "pending" {
+			continue
+		}
+		if due, err := common.ParseDate(task.Due); err == nil {
+			switch {
+			case !due.After(t):
+				add("overdue", task, due, "%q is overdue (due %s)")
+			case !due.After(t.Add(rc.Due)):
+				add("due_soon", task, due, "%q is due %s")
+			}
+		}
+		if sched, err := common.ParseDate(task.Scheduled); err == nil && !sched.After(t.Add(rc.Scheduled)) && sched.After(since.Add(rc.Scheduled)) {
+			add("scheduled", task, sched, "%q is scheduled for %s")
+		}
+		if wait, err := common.ParseDate(task.Wait); err == nil && !wait.After(t) && wait.After(since) {
+			add("unwaited", task, wait, "%q is no longer waiting (since %s)")
+		}
+	}
+	sort.SliceStable(reminders, func(i, j int) bool { return reminders[i].Date < reminders[j].Date })
+	return reminders
+}
+
+var reminderLevels = map[string]string{"overdue": "warning", "due_soon": "notice", "scheduled": "notice", "unwaited": "info"}
+
+// reminderScheduler remembers which reminders have been sent.
+type reminderScheduler struct {
+	notified map[string]bool
+	since    time.Time
+	// catchUp makes the next tick send what has piled up, at startup or after
+	// a pause without clients, as one summary.
+	catchUp bool
+	notify  func(method string, params map[string]any)
+}
+
+// tick sends the reminders that have not been sent before. Only reminders
+// that are still due are remembered, so the set shrinks as tasks are done.
+func (r *reminderScheduler) tick(tasks []Task, rc ReminderConfig, t time.Time) []Reminder {
+	due := DueReminders(tasks, rc, t, r.since, common.DefaultLocation())
+	notified := make(map[string]bool, len(due))
+	var sent []Reminder
+	for _, rem := range due {
+		notified[rem.key()] = true
+		if !r.notified[rem.key()] {
+			sent = append(sent, rem)
+		}
+	}
+	r.notified = notified
+	r.since = t
+	catchUp := r.catchUp
+	r.catchUp = false
+
+	if catchUp && len(sent) > 1 {
+		level, messages := "notice", make([]string, len(sent))
+		for i, rem := range sent {
+			messages[i] = rem.Message
+			if rem.Event == "overdue" {
+				level = "warning"
+			}
+		}
+		r.notify("notifications/message", map[string]any{
+			"level":  level,
+			"logger": "warmcp.reminders",
+			"data": map[string]any{
+				"event":     "summary",
+				"message":   fmt.Sprintf("%d task reminders: %s", len(sent), strings.Join(messages, "; ")),
+				"reminders": sent,
+			},
+		})
+		return sent
+	}
+	for _, rem := range sent {
+		r.notify("notifications/message", map[string]any{
+			"level":  reminderLevels[rem.Event],
+			"logger": "warmcp.reminders",
+			"data":   rem,
+		})
+	}
+	return sent
+}
+
+// WatchReminders periodically checks the pending tasks of the default profile
+// and sends MCP log notifications when they become due, go overdue, reach
+// their scheduled date or come out of waiting. What is already due at startup
+// is sent as one summary, and no checks run while no client is connected.
+// It blocks, so run it in a goroutine.
+func WatchReminders(s *server.MCPServer) {
+	ctx := context.Background()
+	sched := &reminderScheduler{notified: map[string]bool{}, catchUp: true, notify: s.SendNotificationToAllClients}
+	interval := CurrentReminderConfig().Interval
+	sched.since = now().Add(-interval)
+	for {
+		if !common.HasSessions() {
+			// Nobody would see the notifications: check again once a client is connected.
+			sched.catchUp = true
+		} else if err := sched.check(ctx, &interval); err != nil {
+			log.Printf("checking reminders: %v", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// check reads the reminder settings into interval and sends what is due.
+func (r *reminderScheduler) check(ctx context.Context, interval *time.Duration) error {
+	rc := CurrentReminderConfig()
+	*interval = rc.Interval
+	tasks, err := ExportAllTasks(ctx, "status:pending")
+	if err != nil {
+		return err
+	}
+	r.tick(tasks, rc, now())
+	return nil
+}
+
+func remindersHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	rc := CurrentReminderConfig()
+	var err error
+	if lead, ok := argsMap["due_lead"].(string); ok && lead != "" {
+		if rc.Due, err = time.ParseDuration(lead); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid due_lead %q", lead)), nil
+		}
+	}
+	tasks, err := ExportAllTasks(ctx, "status:pending")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	// Report tasks that came out of waiting during the last day.
+	t := now()
+	reminders := DueReminders(tasks, rc, t, t.Add(-24*time.Hour), common.Location(ctx))
+	if reminders == nil {
+		reminders = []Reminder{}
+	}
+	return common.JSONResult(map[string]any{
+		"enabled":        rc.Enabled,
+		"due_lead":       rc.Due.String(),
+		"scheduled_lead": rc.Scheduled.String(),
+		"interval":       rc.Interval.String(),
+		"reminders":      reminders,
+	})
+}
@@ -0,0 +1,288 @@
+package taskwarrior
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"warmcp/pkg/common"
+)
+
+// Formats lists the text renderings supported by task_list and task_report.
+var Formats = []string{"json", "markdown", "org", "csv", "table"}
+
+const noProject = "(no project)"
+
+type projectGroup struct {
+	Project string
+	Tasks   []Task
+}
+
+// groupByProject groups tasks alphabetically by project, keeping the task
+// order inside each group. Tasks without a project come last.
+func groupByProject(tasks []Task) []projectGroup {
+	index := map[string]int{}
+	var groups []projectGroup
+	for _, t := range tasks {
+		name := t.Project
+		if name == "" {
+			name = noProject
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, projectGroup{Project: name})
+		}
+		groups[i].Tasks = append(groups[i].Tasks, t)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if (groups[i].Project == noProject) != (groups[j].Project == noProject) {
+			return groups[j].Project == noProject
+		}
+		return groups[i].Project < groups[j].Project
+	})
+	return groups
+}
+
+// displayDate converts a Taskwarrior date into loc for display.
+func displayDate(s string, loc *time.Location) (time.Time, bool) {
+	t, err := common.ParseDate(s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.In(loc), true
+}
+
+func isDone(t Task) bool {
+	return t.Status == "completed" || t.Status == "deleted"
+}
+
+// RenderTasks renders tasks in one of the text formats, with dates in loc.
+// header, if not empty, is emitted as a note in the idiom of the format (CSV
+// has no room for it).
+func RenderTasks(tasks []Task, format, header string, loc *time.Location) (string, error) {
+	switch format {
+	case "markdown":
+		return renderMarkdown(tasks, header, loc), nil
+	case "org":
+		return renderOrg(tasks, header, loc), nil
+	case "csv":
+		return renderCSV(tasks, loc)
+	case "table":
+		return renderTable(tasks, header, loc), nil
+	}
+	return "", fmt.Errorf("unknown format %q", format)
+}
+
+func renderMarkdown(tasks []Task, header string, loc *time.Location) string {
+	var b strings.Builder
+	if header != "" {
+		fmt.Fprintf(&b, "_%s_\n\n", header)
+	}
+	for i, g := range groupByProject(tasks) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "## %s\n\n", g.Project)
+		for _, t := range g.Tasks {
+			box := " "
+			if isDone(t) {
+				box = "x"
+			}
+			fmt.Fprintf(&b, "- [%s] %s", box, t.Description)
+			var meta []string
+			if d, ok := displayDate(t.Due, loc); ok {
+				meta = append(meta, "due "+d.Format("2006-01-02"))
+			}
+			if t.Priority != "" {
+				meta = append(meta, "priority "+t.Priority)
+			}
+			if len(meta) > 0 {
+				fmt.Fprintf(&b, " (%s)", strings.Join(meta, ", "))
+			}
+			for _, tag := range t.Tags {
+				fmt.Fprintf(&b, " `#%s`", tag)
+			}
+			b.WriteString("\n")
+			for _, a := range t.Annotations {
+				if d, ok := displayDate(a.Entry, loc); ok {
+					fmt.Fprintf(&b, "  - %s: %s\n", d.Format("2006-01-02"), a.Description)
+				} else {
+					fmt.Fprintf(&b, "  - %s\n", a.Description)
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+var orgPriority = map[string]string{"H": "A", "M": "B", "L": "C"}
+
+func orgDate(t time.Time, active bool) string {
+	if active {
+		return t.Format("<2006-01-02 Mon>")
+	}
+	return t.Format("[2006-01-02 Mon]")
+}
+
+func renderOrg(tasks []Task, header string, loc *time.Location) string {
+	var b strings.Builder
+	if header != "" {
+		fmt.Fprintf(&b, "# %s\n", header)
+	}
+	for _, g := range groupByProject(tasks) {
+		fmt.Fprintf(&b, "* %s\n", g.Project)
+		for _, t := range g.Tasks {
+			keyword := "TODO"
+			if isDone(t) {
+				keyword = "DONE"
+			}
+			fmt.Fprintf(&b, "** %s ", keyword)
+			if p, ok := orgPriority[t.Priority]; ok {
+				fmt.Fprintf(&b, "[#%s] ", p)
+			}
+			b.WriteString(t.Description)
+			if len(t.Tags) > 0 {
+				fmt.Fprintf(&b, " :%s:", strings.Join(t.Tags, ":"))
+			}
+			b.WriteString("\n")
+
+			var planning []string
+			if d, ok := displayDate(t.End, loc); ok && isDone(t) {
+				planning = append(planning, "CLOSED: "+orgDate(d, false))
+			}
+			if d, ok := displayDate(t.Due, loc); ok {
+				planning = append(planning, "DEADLINE: "+orgDate(d, true))
+			}
+			if d, ok := displayDate(t.Scheduled, loc); ok {
+				planning = append(planning, "SCHEDULED: "+orgDate(d, true))
+			}
+			if len(planning) > 0 {
+				fmt.Fprintf(&b, "   %s\n", strings.Join(planning, " "))
+			}
+			for _, a := range t.Annotations {
+				if d, ok := displayDate(a.Entry, loc); ok {
+					fmt.Fprintf(&b, "   - %s %s\n", orgDate(d, false), a.Description)
+				} else {
+					fmt.Fprintf(&b, "   - %s\n", a.Description)
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+func csvDate(s string, loc *time.Location) string {
+	if d, ok := displayDate(s, loc); ok {
+		return d.Format(time.RFC3339)
+	}
+	return ""
+}
+
+func renderCSV(tasks []Task, loc *time.Location) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"id", "uuid", "status", "project", "priority", "due", "scheduled", "tags", "description", "annotations"})
+	for _, g := range groupByProject(tasks) {
+		for _, t := range g.Tasks {
+			notes := make([]string, len(t.Annotations))
+			for i, a := range t.Annotations {
+				notes[i] = a.Description
+			}
+			id := ""
+			if t.ID > 0 {
+				id = fmt.Sprint(t.ID)
+			}
+			w.Write([]string{
+				id, t.UUID, t.Status, t.Project, t.Priority,
+				csvDate(t.Due, loc), csvDate(t.Scheduled, loc),
+				strings.Join(t.Tags, " "), t.Description, strings.Join(notes, "; "),
+			})
+		}
+	}
+	w.Flush()
+	return buf.String(), w.Error()
+}
+
+func renderTable(tasks []Task, header string, loc *time.Location) string {
+	var b strings.Builder
+	if header != "" {
+		fmt.Fprintf(&b, "%s\n\n", header)
+	}
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tProject\tPri\tDue\tTags\tDescription")
+	for _, g := range groupByProject(tasks) {
+		for _, t := range g.Tasks {
+			id := "-"
+			if t.ID > 0 {
+				id = fmt.Sprint(t.ID)
+			}
+			due := ""
+			if d, ok := displayDate(t.Due, loc); ok {
+				due = d.Format("2006-01-02")
+			}
+			project := t.Project
+			desc := t.Description
+			if len(t.Annotations) > 0 {
+				desc = fmt.Sprintf("%s [%d]", desc, len(t.Annotations))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", id, project, t.Priority, due, strings.Join(t.Tags, " "), desc)
+		}
+	}
+	w.Flush()
+	return b.String()
+}
+
+// RenderReport renders a report result. CSV and table output keep exactly
+// the report's columns; markdown and org render the underlying tasks.
+func RenderReport(result ReportResult, tasks []Task, format string, loc *time.Location) (string, error) {
+	switch format {
+	case "csv":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		labels := make([]string, len(result.Columns))
+		for i, c := range result.Columns {
+			labels[i] = c.Label
+		}
+		w.Write(labels)
+		for _, row := range result.Rows {
+			w.Write(reportCells(result.Columns, row))
+		}
+		w.Flush()
+		return buf.String(), w.Error()
+	case "table":
+		var b strings.Builder
+		w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+		labels := make([]string, len(result.Columns))
+		for i, c := range result.Columns {
+			labels[i] = c.Label
+		}
+		fmt.Fprintln(w, strings.Join(labels, "\t"))
+		for _, row := range result.Rows {
+			fmt.Fprintln(w, strings.Join(reportCells(result.Columns, row), "\t"))
+		}
+		w.Flush()
+		return b.String(), nil
+	}
+	return RenderTasks(tasks, format, "Report: "+result.Report, loc)
+}
+
+func reportCells(columns []ReportColumn, row map[string]any) []string {
+	cells := make([]string, len(columns))
+	for i, c := range columns {
+		switch v := row[c.Name].(type) {
+		case nil:
+		case []string:
+			cells[i] = strings.Join(v, " ")
+		case string:
+			cells[i] = strings.ReplaceAll(v, "\n", " ")
+		default:
+			cells[i] = fmt.Sprint(v)
+		}
+	}
+	return cells
+}
@@ -0,0 +1,390 @@
+package taskwarrior
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Report is a report.<name>.* definition from taskrc.
+type Report struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Columns     []string `json:"columns"`
+	Labels      []string `json:"labels,omitempty"`
+	Filter      string   `json:"filter,omitempty"`
+	Sort        []string `json:"sort,omitempty"`
+}
+
+// ReportColumn names a column of a report result together with its label.
+type ReportColumn struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+}
+
+// ReportResult holds the rows of a report, with exactly the report's columns.
+type ReportResult struct {
+	Report  string           `json:"report"`
+	Columns []ReportColumn   `json:"columns"`
+	Rows    []map[string]any `json:"rows"`
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// ParseReports extracts the report definitions from a configuration map, sorted by name.
+func ParseReports(cfg map[string]string) []Report {
+	byName := map[string]*Report{}
+	for key, val := range cfg {
+		rest, ok := strings.CutPrefix(key, "report.")
+		if !ok {
+			continue
+		}
+		dot := strings.LastIndex(rest, ".")
+		if dot <= 0 {
+			continue
+		}
+		name, field := rest[:dot], rest[dot+1:]
+		r, ok := byName[name]
+		if !ok {
+			r = &Report{Name: name}
+			byName[name] = r
+		}
+		switch field {
+		case "description":
+			r.Description = val
+		case "columns":
+			r.Columns = splitList(val)
+		case "labels":
+			r.Labels = splitList(val)
+		case "filter":
+			r.Filter = val
+		case "sort":
+			r.Sort = splitList(val)
+		}
+	}
+
+	reports := make([]Report, 0, len(byName))
+	for _, r := range byName {
+		// Only reports with columns can be rendered; this skips helper keys.
+		if len(r.Columns) > 0 {
+			reports = append(reports, *r)
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Name < reports[j].Name })
+	return reports
+}
+
+// attributeValue returns the raw value of a task attribute, or nil when unset.
+func attributeValue(t Task, attr string) any {
+	var v any
+	switch attr {
+	case "id":
+		v = t.ID
+	case "uuid":
+		v = t.UUID
+	case "description":
+		v = t.Description
+	case "status":
+		v = t.Status
+	case "entry":
+		v = t.Entry
+	case "modified":
+		v = t.Modified
+	case "start":
+		v = t.Start
+	case "end":
+		v = t.End
+	case "due":
+		v = t.Due
+	case "scheduled":
+		v = t.Scheduled
+	case "wait":
+		v = t.Wait
+	case "until":
+		v = t.Until
+	case "recur":
+		v = t.Recur
+	case "parent":
+		v = t.Parent
+	case "project":
+		v = t.Project
+	case "priority":
+		v = t.Priority
+	case "tags":
+		if len(t.Tags) > 0 {
+			v = t.Tags
+		}
+	case "depends":
+		if len(t.Depends) > 0 {
+			v = t.Depends
+		}
+	case "urgency":
+		v = t.Urgency
+	default:
+		v = t.UDA[attr]
+	}
+	if s, ok := v.(string); ok && s == "" {
+		return nil
+	}
+	if i, ok := v.(int); ok && i == 0 {
+		return nil
+	}
+	return v
+}
+
+// formatDuration renders a duration compactly, the way Taskwarrior's age columns do.
+func formatDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	switch {
+	case d >= 365*24*time.Hour:
+		return fmt.Sprintf("%s%.1fy", sign, d.Hours()/24/365)
+	case d >= 7*24*time.Hour:
+		return fmt.Sprintf("%s%dw", sign, int(d.Hours()/24/7))
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%s%dd", sign, int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%s%dh", sign, int(d.Hours()))
+	case d >= time.Minute:
+		return fmt.Sprintf("%s%dmin", sign, int(d.Minutes()))
+	}
+	return fmt.Sprintf("%s%ds", sign, int(d.Seconds()))
+}
+
+var dateAttributes = map[string]bool{
+	"entry": true, "modified": true, "start": true, "end": true,
+	"due": true, "scheduled": true, "wait": true, "until": true,
+}
+
+// ColumnValue formats a task attribute for a report column such as
+// "due.relative" or "description.count". Dates are rendered in loc.
+func ColumnValue(t Task, column string, loc *time.Location) any {
+	attr, style, _ := strings.Cut(column, ".")
+	v := attributeValue(t, attr)
+	if v == nil {
+		if attr == "description" {
+			return ""
+		}
+		return nil
+	}
+
+	if dateAttributes[attr] {
+		date, err := common.ParseDate(v.(string))
+		if err != nil {
+			return v
+		}
+		switch style {
+		case "age":
+			return formatDuration(now().Sub(date))
+		case "relative", "countdown", "remaining":
+			return formatDuration(date.Sub(now()))
+		case "epoch":
+			return date.Unix()
+		}
+		return date.In(loc).Format(time.RFC3339)
+	}
+
+	switch style {
+	case "indicator":
+		return attr[:1]
+	case "count":
+		if attr == "description" && len(t.Annotations) > 0 {
+			return fmt.Sprintf("%s [%d]", t.Description, len(t.Annotations))
+		}
+		if list, ok := v.([]string); ok {
+			return fmt.Sprintf("[%d]", len(list))
+		}
+	case "parent":
+		if s, ok := v.(string); ok {
+			parent, _, _ := strings.Cut(s, ".")
+			return parent
+		}
+	case "short":
+		if attr == "uuid" && len(t.UUID) >= 8 {
+			return t.UUID[:8]
+		}
+	case "combined":
+		if attr == "description" && len(t.Annotations) > 0 {
+			lines := []string{t.Description}
+			for _, a := range t.Annotations {
+				lines = append(lines, a.Description)
+			}
+			return strings.Join(lines, "\n")
+		}
+	}
+	return v
+}
+
+// sortKey reduces an attribute to something comparable. Priorities rank H > M > L.
+func sortKey(t Task, attr string) (float64, string, bool) {
+	v := attributeValue(t, attr)
+	if v == nil {
+		return 0, "", false
+	}
+	if attr == "priority" {
+		return float64(strings.Index("LMH", v.(string)) + 1), "", true
+	}
+	switch val := v.(type) {
+	case int:
+		return float64(val), "", true
+	case float64:
+		return val, "", true
+	case string:
+		if f, err := strconv.ParseFloat(val, 64); err == nil && !dateAttributes[attr] {
+			return f, "", true
+		}
+		return math.NaN(), val, true
+	case []string:
+		return math.NaN(), strings.Join(val, " "), true
+	}
+	return math.NaN(), fmt.Sprint(v), true
+}
+
+// SortTasks orders tasks by a report sort spec such as "urgency-,due+,project+/".
+// Tasks missing a sort attribute go last.
+func SortTasks(tasks []Task, spec []string) {
+	sort.SliceStable(tasks, func(i, j int) bool {
+		for _, key := range spec {
+			key = strings.TrimSuffix(key, "/")
+			desc := strings.HasSuffix(key, "-")
+			attr := strings.TrimRight(key, "+-")
+
+			fi, si, oki := sortKey(tasks[i], attr)
+			fj, sj, okj := sortKey(tasks[j], attr)
+			if oki != okj {
+				return oki
+			}
+			if !oki {
+				continue
+			}
+			// Numbers sort before strings whatever the direction, so the
+			// order stays consistent when an attribute mixes both.
+			if numi, numj := !math.IsNaN(fi), !math.IsNaN(fj); numi != numj {
+				return numi
+			}
+			var cmp int
+			switch {
+			case !math.IsNaN(fi):
+				if fi < fj {
+					cmp = -1
+				} else if fi > fj {
+					cmp = 1
+				}
+			default:
+				cmp = strings.Compare(si, sj)
+			}
+			if cmp == 0 {
+				continue
+			}
+			if desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+// BuildReport projects tasks onto the report's columns, in the report's sort order.
+func BuildReport(r Report, tasks []Task, loc *time.Location) ReportResult {
+	SortTasks(tasks, r.Sort)
+	result := ReportResult{Report: r.Name, Rows: []map[string]any{}}
+	for i, col := range r.Columns {
+		label := col
+		if i < len(r.Labels) {
+			label = r.Labels[i]
+		}
+		result.Columns = append(result.Columns, ReportColumn{Name: col, Label: label})
+	}
+	for _, t := range tasks {
+		row := map[string]any{}
+		for _, col := range r.Columns {
+			row[col] = ColumnValue(t, col, loc)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result
+}
+
+func findReport(cfg map[string]string, name string) (Report, bool) {
+	for _, r := range ParseReports(cfg) {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return Report{}, false
+}
+
+func reportHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	name, _ := argsMap["name"].(string)
+	filter, _ := argsMap["filter"].(string)
+	format, _ := argsMap["format"].(string)
+
+	cfg, err := ShowConfig(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	report, ok := findReport(cfg, name)
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown report %q", name)), nil
+	}
+
+	var filters []string
+	if report.Filter != "" {
+		filters = append(filters, "(")
+		filters = append(filters, strings.Fields(report.Filter)...)
+		filters = append(filters, ")")
+	}
+	filters = append(filters, strings.Fields(filter)...)
+	tasks, err := ExportTasks(ctx, filters...)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	result := BuildReport(report, tasks, common.Location(ctx))
+	if format == "" || format == "json" {
+		return common.JSONResult(result)
+	}
+	text, err := RenderReport(result, tasks, format, common.Location(ctx))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(text), nil
+}
+
+func reportsResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	cfg, err := ShowConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(ParseReports(cfg), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      req.Params.URI,
+			MIMEType: "application/json",
+			Text:     string(data),
+		},
+	}, nil
+}
@@ -0,0 +1,174 @@
+package taskwarrior
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"warmcp/pkg/common"
+)
+
+// dateLayout is the ISO 8601 basic format Taskwarrior uses in exports.
+const dateLayout = common.DateLayout
+
+// Annotation is a single timestamped note attached to a task.
+type Annotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// Task mirrors a single object produced by `task export`.
+// Attributes that are not part of the core schema are collected in UDA.
+type Task struct {
+	ID          int            `json:"id,omitempty"`
+	UUID        string         `json:"uuid,omitempty"`
+	Description string         `json:"description"`
+	Status      string         `json:"status"`
+	Entry       string         `json:"entry,omitempty"`
+	Modified    string         `json:"modified,omitempty"`
+	Start       string         `json:"start,omitempty"`
+	End         string         `json:"end,omitempty"`
+	Due         string         `json:"due,omitempty"`
+	Scheduled   string         `json:"scheduled,omitempty"`
+	Wait        string         `json:"wait,omitempty"`
+	Until       string         `json:"until,omitempty"`
+	Recur       string         `json:"recur,omitempty"`
+	Mask        string         `json:"mask,omitempty"`
+	Imask       float64        `json:"imask,omitempty"`
+	Parent      string         `json:"parent,omitempty"`
+	Project     string         `json:"project,omitempty"`
+	Priority    string         `json:"priority,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Depends     []string       `json:"depends,omitempty"`
+	Annotations []Annotation   `json:"annotations,omitempty"`
+	Urgency     float64        `json:"urgency,omitempty"`
+	UDA         map[string]any `json:"-"`
+}
+
+// coreAttributes lists the export keys that map onto Task fields.
+var coreAttributes = map[string]bool{
+	"id": true, "uuid": true, "description": true, "status": true,
+	"entry": true, "modified": true, "start": true, "end": true,
+	"due": true, "scheduled": true, "wait": true, "until": true,
+	"recur": true, "mask": true, "imask": true, "parent": true,
+	"project": true, "priority": true, "tags": true, "depends": true,
+	"annotations": true, "urgency": true,
+}
+
+type taskAlias Task
+
+func (t *Task) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	// Taskwarrior 2.x exports depends as a comma separated string, 3.x as an array.
+	var depends []string
+	if dep, ok := raw["depends"]; ok {
+		var s string
+		if err := json.Unmarshal(dep, &s); err == nil {
+			for _, d := range strings.Split(s, ",") {
+				if d = strings.TrimSpace(d); d != "" {
+					depends = append(depends, d)
+				}
+			}
+		} else if err := json.Unmarshal(dep, &depends); err != nil {
+			return fmt.Errorf("invalid depends: %v", err)
+		}
+		delete(raw, "depends")
+	}
+
+	rest, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	var a taskAlias
+	if err := json.Unmarshal(rest, &a); err != nil {
+		return err
+	}
+	*t = Task(a)
+	t.Depends = depends
+
+	for key, val := range raw {
+		if coreAttributes[key] {
+			continue
+		}
+		var v any
+		if err := json.Unmarshal(val, &v); err != nil {
+			return err
+		}
+		if t.UDA == nil {
+			t.UDA = map[string]any{}
+		}
+		t.UDA[key] = v
+	}
+	return nil
+}
+
+func (t Task) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(taskAlias(t))
+	if err != nil || len(t.UDA) == 0 {
+		return data, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for k, v := range t.UDA {
+		if !coreAttributes[k] {
+			m[k] = v
+		}
+	}
+	return json.Marshal(m)
+}
+
+// HasTag reports whether the task carries the given tag.
+func (t *Task) HasTag(tag string) bool {
+	for _, tg := range t.Tags {
+		if tg == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTasks decodes the output of `task export`.
+func ParseTasks(out string) ([]Task, error) {
+	var tasks []Task
+	if strings.TrimSpace(out) == "" {
+		return tasks, nil
+	}
+	if err := json.Unmarshal([]byte(out), &tasks); err != nil {
+		return nil, fmt.Errorf("could not parse task export: %v", err)
+	}
+	return tasks, nil
+}
+
+// ExportTasks runs `task <filters> export` and returns the typed result.
+func ExportTasks(ctx context.Context, filters ...string) ([]Task, error) {
+	cmd := &TaskCommand{
+		Filters: filters,
+		Command: "export",
+	}
+	out, err := cmd.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTasks(out)
+}
+
+// ExportAllTasks is ExportTasks with the active context switched off, for
+// views that must cover every task whatever the user is focused on.
+func ExportAllTasks(ctx context.Context, filters ...string) ([]Task, error) {
+	cmd := &TaskCommand{
+		Overrides: []string{"rc.context=none"},
+		Filters:   filters,
+		Command:   "export",
+	}
+	out, err := cmd.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTasks(out)
+}
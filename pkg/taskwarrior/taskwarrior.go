@@ -2,8 +2,10 @@ package taskwarrior
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"os"
+	"regexp"
+	"sort"
 	"strings"
 	"warmcp/pkg/common"
 
@@ -13,15 +15,20 @@ import (
 
 var baseArgs = []string{"rc.confirmation=off", "rc.verbose=nothing", "rc.hooks=on"}
 
+// createdUUIDPattern extracts the UUID reported by `task add` under rc.verbose=new-uuid.
+var createdUUIDPattern = regexp.MustCompile(`Created task ([0-9a-fA-F-]{36})`)
+
 // TaskCommand represents a structured Taskwarrior command.
 type TaskCommand struct {
 	Overrides     []string
 	Filters       []string
 	Command       string
 	Modifications []string
+	// Env adds variables such as TZ to the command's environment.
+	Env []string
 }
 
-func (c *TaskCommand) Run() (string, error) {
+func (c *TaskCommand) Run(ctx context.Context) (string, error) {
 	args := append([]string{}, c.Overrides...)
 	args = append(args, c.Filters...)
 	if c.Command != "" {
@@ -29,24 +36,62 @@ func (c *TaskCommand) Run() (string, error) {
 	}
 	args = append(args, c.Modifications...)
 
-	env := []string{
-		fmt.Sprintf("TASKRC=%s", common.GetTaskrcPath()),
+	cfg := common.CurrentConfig()
+	env := append(common.TaskEnv(ctx), c.Env...)
+	base := append(append([]string{}, baseArgs...), cfg.Task.Overrides...)
+	return common.RunCommand(ctx, cfg.Binaries.Task, env, base, args...)
+}
+
+// attributeArgs converts the structured task fields of a tool call into
+// Taskwarrior attribute arguments.
+func attributeArgs(argsMap map[string]any) []string {
+	var args []string
+	for _, attr := range []string{"project", "due", "scheduled", "priority"} {
+		if v, ok := argsMap[attr].(string); ok && v != "" {
+			args = append(args, fmt.Sprintf("%s:%s", attr, v))
+		}
 	}
-	return common.RunCommand("task", env, baseArgs, args...)
+	for _, tag := range stringSlice(argsMap["tags"]) {
+		args = append(args, "+"+strings.TrimPrefix(tag, "+"))
+	}
+	if deps := stringSlice(argsMap["depends"]); len(deps) > 0 {
+		args = append(args, "depends:"+strings.Join(deps, ","))
+	}
+	if udas, ok := argsMap["udas"].(map[string]any); ok {
+		names := make([]string, 0, len(udas))
+		for name := range udas {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			args = append(args, fmt.Sprintf("%s:%v", name, udas[name]))
+		}
+	}
+	return args
 }
 
-func RegisterHandlers(s *server.MCPServer) {
-	s.AddTool(mcp.NewTool("task_add",
-		mcp.WithDescription("Create a new task. PROMPT FOR CONFIRMATION."),
-		mcp.WithString("description", mcp.Required(), mcp.Description("Task description")),
-		mcp.WithString("metadata", mcp.Description("Attributes like 'project:Home due:2pm +next'")),
-	), addHandler)
+// stringSlice accepts either a JSON array of strings or a single string.
+func stringSlice(v any) []string {
+	switch val := v.(type) {
+	case []string:
+		return val
+	case []any:
+		out := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	case string:
+		return strings.Fields(val)
+	}
+	return nil
+}
 
-	s.AddTool(mcp.NewTool("task_modify",
-		mcp.WithDescription("Modify tasks. Can take filters and multiple modifications. PROMPT FOR CONFIRMATION."),
-		mcp.WithString("filter", mcp.Description("Filter for tasks to modify (e.g., '+PENDING project:Work')")),
-		mcp.WithString("modifications", mcp.Required(), mcp.Description("Modifications to apply (e.g., 'project:New /old/new/ +tag')")),
-	), modifyHandler)
+func RegisterHandlers(s *server.MCPServer) {
+	loadAllUDAs(context.Background())
+	registerUDATools(s, allUDAs())
 
 	s.AddTool(mcp.NewTool("task_done",
 		mcp.WithDescription("Mark a task as done. PROMPT FOR CONFIRMATION."),
@@ -59,10 +104,34 @@ func RegisterHandlers(s *server.MCPServer) {
 	), deleteHandler)
 
 	s.AddTool(mcp.NewTool("task_list",
-		mcp.WithDescription("List tasks (export JSON). NO CONFIRMATION NEEDED."),
-		mcp.WithString("filter", mcp.Description("Filter string. Default: status:pending")),
+		mcp.WithDescription("List tasks (export JSON, with dates in RFC 3339 in the requested timezone) along with the Taskwarrior context that was applied. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		common.WithTimezone(),
+		mcp.WithString("filter", mcp.Description("Filter string. Default: the configured default filter, status:pending unless changed")),
+		mcp.WithBoolean("ignore_context", mcp.Description("Ignore the active context (rc.context=none)")),
+		mcp.WithString("format", mcp.Enum(Formats...), mcp.Description("Output format. Default: json")),
 	), listHandler)
 
+	s.AddTool(mcp.NewTool("task_context_list",
+		mcp.WithDescription("List defined contexts and which one is active. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), contextListHandler)
+
+	s.AddTool(mcp.NewTool("task_context_define",
+		mcp.WithDescription("Define a context as a named filter. PROMPT FOR CONFIRMATION."),
+		mcp.WithString("name", mcp.Required(), mcp.Description("Context name")),
+		mcp.WithString("filter", mcp.Required(), mcp.Description("Filter applied while the context is active")),
+	), contextDefineHandler)
+
+	s.AddTool(mcp.NewTool("task_context_set",
+		mcp.WithDescription("Activate a context. PROMPT FOR CONFIRMATION."),
+		mcp.WithString("name", mcp.Required(), mcp.Description("Context name")),
+	), contextSetHandler)
+
+	s.AddTool(mcp.NewTool("task_context_clear",
+		mcp.WithDescription("Clear the active context. PROMPT FOR CONFIRMATION."),
+	), contextClearHandler)
+
 	s.AddTool(mcp.NewTool("task_annotate",
 		mcp.WithDescription("Add annotation. PROMPT FOR CONFIRMATION."),
 		mcp.WithString("uuid", mcp.Required(), mcp.Description("UUID of the task")),
@@ -91,9 +160,135 @@ func RegisterHandlers(s *server.MCPServer) {
 
 	s.AddTool(mcp.NewTool("task_calc",
 		mcp.WithDescription("Evaluate Taskwarrior date math. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithString("expression", mcp.Required(), mcp.Description("Math expression")),
 	), calcHandler)
 
+	s.AddTool(mcp.NewTool("task_resolve_date",
+		mcp.WithDescription("Resolve a date phrase to an absolute timestamp before using it in task_add, task_modify or timew track. Accepts Taskwarrior synonyms (eow, som, monday, 2wks) and phrases like 'next friday at 3pm' or 'in 10 days'. Returns ISO 8601 in local time plus the Taskwarrior form. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		common.WithTimezone(),
+		mcp.WithString("expression", mcp.Required(), mcp.Description("Date phrase or Taskwarrior date expression")),
+	), resolveDateHandler)
+
+	s.AddTool(mcp.NewTool("task_explain_urgency",
+		mcp.WithDescription("Break down a task's urgency term by term from the urgency.* coefficients. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		common.WithTimezone(),
+		mcp.WithString("uuid", mcp.Required(), mcp.Description("UUID of the task")),
+		mcp.WithBoolean("include_information", mcp.Description("Also include `task <uuid> information` output as a cross-check")),
+	), explainUrgencyHandler)
+
+	s.AddTool(mcp.NewTool("task_depend_add",
+		mcp.WithDescription("Make a task depend on other tasks, refusing changes that would create a cycle. PROMPT FOR CONFIRMATION."),
+		mcp.WithString("uuid", mcp.Required(), mcp.Description("UUID, UUID prefix or ID of the dependent task")),
+		mcp.WithArray("depends", mcp.Required(), mcp.WithStringItems(), mcp.Description("UUIDs, UUID prefixes or IDs of the tasks it depends on")),
+	), dependAddHandler)
+
+	s.AddTool(mcp.NewTool("task_depend_remove",
+		mcp.WithDescription("Remove dependencies from a task. PROMPT FOR CONFIRMATION."),
+		mcp.WithString("uuid", mcp.Required(), mcp.Description("UUID of the dependent task")),
+		mcp.WithArray("depends", mcp.Required(), mcp.WithStringItems(), mcp.Description("UUIDs of the dependencies to remove")),
+	), dependRemoveHandler)
+
+	s.AddTool(mcp.NewTool("task_graph",
+		mcp.WithDescription("Dependency graph for a filter with critical path, blocked tasks and tasks that unblock the most work. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		common.WithTimezone(),
+		mcp.WithString("filter", mcp.Description("Filter string. Default: the configured default filter, status:pending unless changed")),
+		mcp.WithString("format", mcp.Enum("mermaid", "dot", "json"), mcp.Description("Diagram format included with the JSON graph. Default: mermaid")),
+	), graphHandler)
+
+	s.AddTool(mcp.NewTool("task_recur_add",
+		mcp.WithDescription("Create a recurring task from a validated recur period. PROMPT FOR CONFIRMATION."),
+		common.WithTimezone(),
+		mcp.WithString("description", mcp.Required(), mcp.Description("Task description")),
+		mcp.WithString("recur", mcp.Required(), mcp.Description("Recurrence period (e.g. 'daily', 'weekly', '2wks', 'P1M')")),
+		mcp.WithString("due", mcp.Required(), mcp.Description("Due date of the first instance")),
+		mcp.WithString("until", mcp.Description("Date after which no more instances are generated")),
+		mcp.WithString("project", mcp.Description("Project name")),
+		mcp.WithArray("tags", mcp.WithStringItems(), mcp.Description("Tags without the leading '+'")),
+		mcp.WithString("priority", mcp.Enum("H", "M", "L"), mcp.Description("Priority")),
+		mcp.WithString("metadata", mcp.Description("Additional attributes")),
+	), recurAddHandler)
+
+	s.AddTool(mcp.NewTool("task_recur_list",
+		mcp.WithDescription("List recurrence templates with their upcoming instances. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		common.WithTimezone(),
+		mcp.WithString("filter", mcp.Description("Additional filter applied to the templates")),
+	), recurListHandler)
+
+	s.AddTool(mcp.NewTool("task_recur_modify",
+		mcp.WithDescription("Modify a recurrence template, optionally propagating to its pending instances. PROMPT FOR CONFIRMATION."),
+		mcp.WithString("uuid", mcp.Required(), mcp.Description("UUID of the recurrence template")),
+		mcp.WithString("modifications", mcp.Description("Modifications to apply (e.g., 'project:New +tag')")),
+		mcp.WithString("recur", mcp.Description("New recurrence period")),
+		mcp.WithObject("udas", mcp.Description("User Defined Attribute values keyed by UDA name")),
+		mcp.WithBoolean("propagate", mcp.Description("Also apply the change to instances that are not done yet, pending or waiting")),
+	), recurModifyHandler)
+
+	s.AddTool(mcp.NewTool("task_recur_stop",
+		mcp.WithDescription("Stop a recurrence by deleting its template. PROMPT FOR CONFIRMATION."),
+		mcp.WithString("uuid", mcp.Required(), mcp.Description("UUID of the recurrence template")),
+		mcp.WithBoolean("delete_pending", mcp.Description("Also delete instances that are not done yet, pending or waiting")),
+	), recurStopHandler)
+
+	s.AddTool(mcp.NewTool("task_report",
+		mcp.WithDescription("Run a named report (e.g. next, waiting) and return its columns as structured rows. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		common.WithTimezone(),
+		mcp.WithString("name", mcp.Required(), mcp.Description("Report name as configured in taskrc")),
+		mcp.WithString("filter", mcp.Description("Additional filter combined with the report's own filter")),
+		mcp.WithString("format", mcp.Enum(Formats...), mcp.Description("Output format. Default: json")),
+	), reportHandler)
+
+	s.AddResource(mcp.Resource{
+		URI:         "task://reports",
+		Name:        "Taskwarrior Reports",
+		Description: "Reports configured in taskrc with their columns, filter and sort",
+		MIMEType:    "application/json",
+	}, reportsResourceHandler)
+
+	s.AddTool(mcp.NewTool("task_analytics",
+		mcp.WithDescription("Created vs completed, burndown, overdue counts, throughput and median lead time over a date range, from the full export whatever context is active. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		common.WithTimezone(),
+		mcp.WithString("start", mcp.Description("First day (YYYY-MM-DD). Default: 30 days or 12 weeks before end")),
+		mcp.WithString("end", mcp.Description("Last day (YYYY-MM-DD). Default: today")),
+		mcp.WithString("interval", mcp.Enum("day", "week"), mcp.Description("Period length. Default: day")),
+		mcp.WithString("group_by", mcp.Enum("project", "tag"), mcp.Description("Produce one set of series per project or tag")),
+		mcp.WithString("project", mcp.Description("Only include tasks in this project")),
+		mcp.WithString("tag", mcp.Description("Only include tasks with this tag")),
+	), analyticsHandler)
+
+	s.AddTool(mcp.NewTool("task_export_ical",
+		mcp.WithDescription("Export tasks as an RFC 5545 calendar of VTODOs. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithString("filter", mcp.Description("Filter string. Default: "+defaultCalendarFilter)),
+	), exportICalHandler)
+
+	s.AddResource(mcp.Resource{
+		URI:         "task://calendar.ics",
+		Name:        "Taskwarrior Calendar",
+		Description: "Pending tasks with a due date as iCalendar VTODOs",
+		MIMEType:    "text/calendar",
+	}, calendarResourceHandler)
+
+	s.AddResourceTemplate(mcp.NewResourceTemplate(
+		"task://calendar.ics{?filter}",
+		"Taskwarrior Calendar (filtered)",
+		mcp.WithTemplateDescription("Tasks matching a filter as iCalendar VTODOs"),
+		mcp.WithTemplateMIMEType("text/calendar"),
+	), calendarResourceHandler)
+
+	s.AddTool(mcp.NewTool("task_reminders",
+		mcp.WithDescription("List the reminders that apply now: overdue tasks, tasks due within the lead time, tasks reaching their scheduled date and tasks that came out of waiting in the last day. Lead times come from the reminders section of the warmcp config file. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		common.WithTimezone(),
+		mcp.WithString("due_lead", mcp.Description("Override how far ahead due dates are reported, e.g. '30m' or '24h'")),
+	), remindersHandler)
+
 	s.AddTool(mcp.NewTool("task_raw",
 		mcp.WithDescription("Run raw task command. PROMPT FOR CONFIRMATION."),
 		mcp.WithString("command", mcp.Required(), mcp.Description("Full task command arguments")),
@@ -109,6 +304,7 @@ func RegisterHandlers(s *server.MCPServer) {
 		mcp.WithDescription("Permanently remove tasks from the database. PROMPT FOR CONFIRMATION."),
 		mcp.WithString("filter", mcp.Required(), mcp.Description("Filter for tasks to purge")),
 	), purgeHandler)
+	common.RequireVersion("task_purge", "task", "2.6.0")
 
 	s.AddTool(mcp.NewTool("task_append",
 		mcp.WithDescription("Append text to a task's description. PROMPT FOR CONFIRMATION."),
@@ -123,46 +319,108 @@ func RegisterHandlers(s *server.MCPServer) {
 	), prependHandler)
 
 	s.AddTool(mcp.NewTool("task_import",
-		mcp.WithDescription("Import tasks from JSON format. PROMPT FOR CONFIRMATION."),
+		mcp.WithDescription("Validate and import tasks from JSON format. Like task import, a task with the UUID of an existing one replaces it. PROMPT FOR CONFIRMATION unless dry_run."),
 		mcp.WithString("json_data", mcp.Required(), mcp.Description("JSON string of tasks to import")),
+		mcp.WithBoolean("dry_run", mcp.Description("Report which tasks would be created or overwritten, with field-level diffs, without importing")),
+		mcp.WithBoolean("merge", mcp.Description("Merge into existing tasks instead of replacing them: attributes the payload leaves out are kept and annotations are added")),
 	), importHandler)
 
+	s.AddTool(mcp.NewTool("task_import_ical",
+		mcp.WithDescription("Import iCalendar VTODO items as tasks. Re-importing the same UID updates the same task. PROMPT FOR CONFIRMATION unless dry_run."),
+		common.WithTimezone(),
+		mcp.WithString("ics_data", mcp.Required(), mcp.Description("iCalendar (.ics) content")),
+		mcp.WithBoolean("dry_run", mcp.Description("Report what would be created, updated or skipped without importing")),
+	), importICalHandler)
+
+	s.AddTool(mcp.NewTool("task_import_todotxt",
+		mcp.WithDescription("Import todo.txt items as tasks. PROMPT FOR CONFIRMATION unless dry_run."),
+		common.WithTimezone(),
+		mcp.WithString("todo_txt", mcp.Required(), mcp.Description("todo.txt content, one item per line")),
+		mcp.WithBoolean("dry_run", mcp.Description("Preview what would be created, updated or skipped without importing")),
+	), importTodoTxtHandler)
+
+	s.AddTool(mcp.NewTool("task_export_todotxt",
+		mcp.WithDescription("Export tasks in todo.txt format. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		common.WithTimezone(),
+		mcp.WithString("filter", mcp.Description("Filter string. Default: the configured default filter, status:pending unless changed")),
+	), exportTodoTxtHandler)
+
 	s.AddTool(mcp.NewTool("task_tags",
 		mcp.WithDescription("List all unique tags. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
 	), tagsHandler)
 
 	s.AddTool(mcp.NewTool("task_projects",
 		mcp.WithDescription("List all unique projects. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
 	), projectsHandler)
 
 	s.AddTool(mcp.NewTool("task_udas",
 		mcp.WithDescription("List all User Defined Attributes. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
 	), udasHandler)
 
 	s.AddTool(mcp.NewTool("task_diagnostics",
 		mcp.WithDescription("Show Taskwarrior diagnostic information (config, version, environment). NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
 	), diagnosticsHandler)
 
 	s.AddTool(mcp.NewTool("task_stats",
 		mcp.WithDescription("Show database statistics. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
 	), statsHandler)
 }
 
+// taskList is the task_list response: the export plus the context it was filtered by.
+type taskList struct {
+	Context string          `json:"context"`
+	Tasks   json.RawMessage `json:"tasks"`
+}
+
 func listHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	argsMap, _ := req.Params.Arguments.(map[string]any)
 	filter, _ := argsMap["filter"].(string)
+	ignoreContext, _ := argsMap["ignore_context"].(bool)
+	format, _ := argsMap["format"].(string)
 	if filter == "" {
-		filter = "status:pending"
+		filter = common.CurrentConfig().Task.DefaultFilter
 	}
+
+	applied := noContext
 	cmd := &TaskCommand{
 		Filters: strings.Fields(filter),
 		Command: "export",
 	}
-	out, err := cmd.Run()
+	if ignoreContext {
+		cmd.Overrides = []string{"rc.context=none"}
+	} else {
+		name, err := activeContext(ctx)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		applied = name
+	}
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	return mcp.NewToolResultText(out), nil
+	if strings.TrimSpace(out) == "" {
+		out = "[]"
+	}
+	if format == "" || format == "json" {
+		return common.LocalJSONResult(ctx, taskList{Context: applied, Tasks: json.RawMessage(out)})
+	}
+
+	tasks, err := ParseTasks(out)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	text, err := RenderTasks(tasks, format, "Context: "+applied, common.Location(ctx))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(text), nil
 }
 
 func addHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -170,15 +428,41 @@ func addHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResu
 	desc, _ := argsMap["description"].(string)
 	meta, _ := argsMap["metadata"].(string)
 
+	mods := []string{desc}
+	mods = append(mods, attributeArgs(argsMap)...)
+	mods = append(mods, strings.Fields(meta)...)
+
+	task, err := addTask(ctx, mods)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return common.LocalJSONResult(ctx, task)
+}
+
+// addTask runs `task add` with the given modifications and returns the created task.
+func addTask(ctx context.Context, mods []string) (Task, error) {
 	cmd := &TaskCommand{
+		Overrides:     []string{"rc.verbose=new-uuid"},
 		Command:       "add",
-		Modifications: append([]string{desc}, strings.Fields(meta)...),
+		Modifications: mods,
 	}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+		return Task{}, err
 	}
-	return mcp.NewToolResultText(out), nil
+
+	filter := "+LATEST"
+	if m := createdUUIDPattern.FindStringSubmatch(out); m != nil {
+		filter = m[1]
+	}
+	tasks, err := ExportTasks(ctx, filter)
+	if err != nil {
+		return Task{}, err
+	}
+	if len(tasks) == 0 {
+		return Task{}, fmt.Errorf("task was created but could not be exported: %s", out)
+	}
+	return tasks[0], nil
 }
 
 func modifyHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -186,12 +470,16 @@ func modifyHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolR
 	filter, _ := argsMap["filter"].(string)
 	mods, _ := argsMap["modifications"].(string)
 
+	modifications := append(strings.Fields(mods), attributeArgs(map[string]any{"udas": argsMap["udas"]})...)
+	if len(modifications) == 0 {
+		return mcp.NewToolResultError("modifications or udas is required"), nil
+	}
 	cmd := &TaskCommand{
 		Filters:       strings.Fields(filter),
 		Command:       "modify",
-		Modifications: strings.Fields(mods),
+		Modifications: modifications,
 	}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -205,7 +493,7 @@ func doneHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRes
 		Filters: []string{uuid},
 		Command: "done",
 	}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -219,7 +507,7 @@ func deleteHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolR
 		Filters: []string{uuid},
 		Command: "delete",
 	}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -235,7 +523,7 @@ func annotateHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToo
 		Command:       "annotate",
 		Modifications: []string{text},
 	}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -251,7 +539,7 @@ func denoteHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolR
 		Command:       "denote",
 		Modifications: []string{text},
 	}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -265,7 +553,7 @@ func startHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRe
 		Filters: []string{uuid},
 		Command: "start",
 	}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -279,7 +567,7 @@ func stopHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRes
 		Filters: []string{uuid},
 		Command: "stop",
 	}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -290,7 +578,7 @@ func undoHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRes
 	cmd := &TaskCommand{
 		Command: "undo",
 	}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -304,7 +592,7 @@ func calcHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRes
 		Command:       "calc",
 		Modifications: []string{expr},
 	}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -322,7 +610,7 @@ func rawHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResu
 		cmd.Modifications = fields[1:]
 	}
 
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -341,7 +629,7 @@ func configHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolR
 			cmd.Modifications = append(cmd.Modifications, val)
 		}
 	}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -355,7 +643,7 @@ func purgeHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRe
 		Filters: strings.Fields(filter),
 		Command: "purge",
 	}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -371,7 +659,7 @@ func appendHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolR
 		Command:       "append",
 		Modifications: []string{text},
 	}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -387,7 +675,7 @@ func prependHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTool
 		Command:       "prepend",
 		Modifications: []string{text},
 	}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -397,31 +685,49 @@ func prependHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTool
 func importHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	argsMap, _ := req.Params.Arguments.(map[string]any)
 	data, _ := argsMap["json_data"].(string)
+	dryRun, _ := argsMap["dry_run"].(bool)
+	merge, _ := argsMap["merge"].(bool)
 
-	tmpFile, err := os.CreateTemp("", "task_import_*.json")
+	objects, err := decodeImport(data)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	defer os.Remove(tmpFile.Name())
-	if _, err := tmpFile.WriteString(data); err != nil {
-		return mcp.NewToolResultError(err.Error()), nil
+	var udas []UDA
+	if hasUDAKeys(objects) {
+		if udas, err = knownUDAs(ctx); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+	}
+	tasks, issues := ValidateImport(objects, udas)
+	if len(issues) > 0 {
+		lines := make([]string, len(issues))
+		for i, issue := range issues {
+			lines[i] = issue.String()
+		}
+		return mcp.NewToolResultError("import rejected:\n" + strings.Join(lines, "\n")), nil
+	}
+	// task import makes tasks without a status pending.
+	for i := range tasks {
+		if tasks[i].Status == "" {
+			tasks[i].Status = "pending"
+		}
 	}
-	tmpFile.Close()
 
-	cmd := &TaskCommand{
-		Command:       "import",
-		Modifications: []string{tmpFile.Name()},
+	var report ImportReport
+	if merge {
+		report, err = runImport(ctx, tasks, nil, true, dryRun)
+	} else {
+		report, err = replaceImport(ctx, objects, tasks, dryRun)
 	}
-	out, err := cmd.Run()
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	return mcp.NewToolResultText(out), nil
+	return common.JSONResult(report)
 }
 
 func tagsHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	cmd := &TaskCommand{Command: "tags"}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -430,7 +736,7 @@ func tagsHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRes
 
 func projectsHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	cmd := &TaskCommand{Command: "projects"}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -439,7 +745,7 @@ func projectsHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToo
 
 func udasHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	cmd := &TaskCommand{Command: "udas"}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -448,7 +754,7 @@ func udasHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRes
 
 func diagnosticsHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	cmd := &TaskCommand{Command: "diagnostics"}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -457,7 +763,7 @@ func diagnosticsHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.Call
 
 func statsHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	cmd := &TaskCommand{Command: "stats"}
-	out, err := cmd.Run()
+	out, err := cmd.Run(ctx)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -2,49 +2,112 @@ package taskwarrior
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 	"warmcp/pkg/common"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 	"github.com/stretchr/testify/assert"
 )
 
 type MockRunner struct {
+	LastCtx  context.Context
 	LastCmd  string
 	LastEnv  []string
 	LastArgs []string
+	Calls    [][]string
 	Output   string
-	Err      error
+	// Outputs, when set, is consumed one entry per call before falling back to Output.
+	Outputs []string
+	Err     error
+	// OnRun, when set, sees the arguments of every call, while any temporary files still exist.
+	OnRun func(args []string)
 }
 
-func (m *MockRunner) Run(name string, env []string, baseArgs []string, args ...string) (string, error) {
+func (m *MockRunner) Run(ctx context.Context, name string, env []string, baseArgs []string, args ...string) (string, error) {
+	m.LastCtx = ctx
 	m.LastCmd = name
 	m.LastEnv = env
-	m.LastArgs = append(baseArgs, args...)
+	m.LastArgs = append(append([]string{}, baseArgs...), args...)
+	m.Calls = append(m.Calls, m.LastArgs)
+	if m.OnRun != nil {
+		m.OnRun(m.LastArgs)
+	}
+	if len(m.Outputs) > 0 {
+		out := m.Outputs[0]
+		m.Outputs = m.Outputs[1:]
+		return out, m.Err
+	}
 	return m.Output, m.Err
 }
 
+func TestTaskCommandContext(t *testing.T) {
+	mock := &MockRunner{}
+	common.Runner = mock
+
+	// The request context reaches the runner, so cancelling it kills the process.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := (&TaskCommand{Command: "export"}).Run(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, ctx, mock.LastCtx)
+}
+
 func TestTaskAdd(t *testing.T) {
-	mock := &MockRunner{Output: "Created task 1."}
+	uuid := "a1b2c3d4-0000-4000-8000-000000000001"
+	mock := &MockRunner{Outputs: []string{
+		"Created task " + uuid + ".",
+		`[{"id":1,"uuid":"` + uuid + `","description":"Buy milk","status":"pending","project":"Home","tags":["errand"],"estimate":2}]`,
+	}}
 	common.Runner = mock
 
 	req := mcp.CallToolRequest{}
 	req.Params.Arguments = map[string]any{
 		"description": "Buy milk",
-		"metadata":    "project:Home",
+		"project":     "Home",
+		"tags":        []any{"errand"},
+		"priority":    "H",
+		"udas":        map[string]any{"estimate": 2},
 	}
 
 	res, err := addHandler(context.Background(), req)
 	assert.NoError(t, err)
-	assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "Created task 1.")
+	assert.False(t, res.IsError)
 	assert.Equal(t, "task", mock.LastCmd)
-	assert.Contains(t, mock.LastArgs, "Buy milk")
-	assert.Contains(t, mock.LastArgs, "project:Home")
+
+	add := mock.Calls[0]
+	assert.Contains(t, add, "rc.verbose=new-uuid")
+	assert.Contains(t, add, "Buy milk")
+	assert.Contains(t, add, "project:Home")
+	assert.Contains(t, add, "+errand")
+	assert.Contains(t, add, "priority:H")
+	assert.Contains(t, add, "estimate:2")
+	assert.Contains(t, mock.Calls[1], uuid)
+	assert.Contains(t, mock.Calls[1], "export")
+
+	var task Task
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(mcp.TextContent).Text), &task))
+	assert.Equal(t, uuid, task.UUID)
+	assert.Equal(t, "Home", task.Project)
+	assert.Equal(t, float64(2), task.UDA["estimate"])
+}
+
+func TestParseTasksDependsFormats(t *testing.T) {
+	tasks, err := ParseTasks(`[{"uuid":"a","depends":"b,c"},{"uuid":"d","depends":["e"]}]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, tasks[0].Depends)
+	assert.Equal(t, []string{"e"}, tasks[1].Depends)
+	assert.Nil(t, tasks[0].UDA)
 }
 
 func TestTaskList(t *testing.T) {
-	mock := &MockRunner{Output: "[{\"description\":\"Task 1\"}]"}
+	mock := &MockRunner{Outputs: []string{"work", "[{\"description\":\"Task 1\"}]"}}
 	common.Runner = mock
 
 	req := mcp.CallToolRequest{}
@@ -55,11 +118,40 @@ func TestTaskList(t *testing.T) {
 	res, err := listHandler(context.Background(), req)
 	assert.NoError(t, err)
 	assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "Task 1")
+	assert.Contains(t, res.Content[0].(mcp.TextContent).Text, `"context":"work"`)
 	assert.Equal(t, "task", mock.LastCmd)
 	assert.Contains(t, mock.LastArgs, "+PENDING")
 	assert.Contains(t, mock.LastArgs, "export")
 }
 
+func TestTaskListIgnoreContext(t *testing.T) {
+	mock := &MockRunner{Output: "[]"}
+	common.Runner = mock
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"ignore_context": true}
+
+	res, err := listHandler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Len(t, mock.Calls, 1)
+	assert.Contains(t, mock.LastArgs, "rc.context=none")
+	assert.Contains(t, res.Content[0].(mcp.TextContent).Text, `"context":"none"`)
+}
+
+func TestParseContexts(t *testing.T) {
+	cfg := map[string]string{
+		"context":            "work",
+		"context.work.read":  "project:Work",
+		"context.work.write": "project:Work",
+		"context.home":       "+home",
+	}
+	contexts := ParseContexts(cfg)
+	assert.Equal(t, []TaskContext{
+		{Name: "home", ReadFilter: "+home"},
+		{Name: "work", ReadFilter: "project:Work", WriteFilter: "project:Work", Active: true},
+	}, contexts)
+}
+
 func TestTaskModifyComplex(t *testing.T) {
 	mock := &MockRunner{Output: "Modified 1 task."}
 	common.Runner = mock
@@ -139,3 +231,821 @@ func TestTaskImport(t *testing.T) {
 	// Verify that the argument looks like a temp file path
 	assert.Contains(t, mock.LastArgs[len(mock.LastArgs)-1], "task_import")
 }
+
+func TestValidateImport(t *testing.T) {
+	udas := []UDA{{Name: "estimate", Type: "numeric"}, {Name: "size", Type: "string", Values: []string{"S", "M", "L"}}}
+	data := `[
+		{"description":"ok","uuid":"9d6a2c1e-8b1f-4c4e-9a55-1c2b3d4e5f60","due":"20240105T120000Z","estimate":3,"size":"M"},
+		{"status":"open","uuid":"nope","due":"friday","colour":"red","size":"XL","estimate":"3"}
+	]`
+	objects, err := decodeImport(data)
+	assert.NoError(t, err)
+	tasks, issues := ValidateImport(objects, udas)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, 3.0, tasks[0].UDA["estimate"])
+
+	var fields []string
+	for _, issue := range issues {
+		assert.Equal(t, 1, issue.Index)
+		fields = append(fields, issue.Field)
+	}
+	assert.ElementsMatch(t, []string{"description", "status", "uuid", "due", "colour", "size", "estimate"}, fields)
+
+	_, err = decodeImport("[{")
+	assert.Error(t, err)
+}
+
+func TestValidateImportRecurring(t *testing.T) {
+	// A recurring template and one of its instances, as `task export` writes them.
+	data := `[
+{"id":0,"description":"Water plants","due":"20240101T080000Z","entry":"20231231T100000Z","last":"1","mask":"-","modified":"20240101T080000Z","recur":"weekly","rtype":"periodic","status":"recurring","uuid":"5f0c7c2e-3b8e-4b8c-9b1e-2f6d1c0a9e11","urgency":-0.2},
+{"id":3,"description":"Water plants","due":"20240101T080000Z","entry":"20240101T080000Z","imask":0,"modified":"20240101T080000Z","parent":"5f0c7c2e-3b8e-4b8c-9b1e-2f6d1c0a9e11","recur":"weekly","rtype":"periodic","template":"5f0c7c2e-3b8e-4b8c-9b1e-2f6d1c0a9e11","status":"pending","uuid":"0b7e1d9a-6c4f-4e2b-8a3d-7f5e9c1b2a40","urgency":8.8}
+]`
+	objects, err := decodeImport(data)
+	assert.NoError(t, err)
+	assert.False(t, hasUDAKeys(objects))
+	tasks, issues := ValidateImport(objects, nil)
+	assert.Empty(t, issues)
+
+	out, err := json.Marshal(tasks)
+	assert.NoError(t, err)
+	var again []map[string]any
+	assert.NoError(t, json.Unmarshal(out, &again))
+	assert.Equal(t, "1", again[0]["last"])
+	assert.Equal(t, "periodic", again[1]["rtype"])
+	assert.Equal(t, "5f0c7c2e-3b8e-4b8c-9b1e-2f6d1c0a9e11", again[1]["template"])
+
+	common.Runner = &MockRunner{Outputs: []string{"[]", "Imported 2 tasks."}}
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"json_data": data}
+	res, err := importHandler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.False(t, res.IsError)
+	assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "Imported 2 tasks.")
+}
+
+func TestTaskImportRejectsInvalid(t *testing.T) {
+	mock := &MockRunner{}
+	common.Runner = mock
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"json_data": `[{"description":"x","status":"open"}]`}
+	res, err := importHandler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.True(t, res.IsError)
+	assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "task 0: status: invalid value open")
+	assert.Empty(t, mock.Calls)
+}
+
+func TestTaskImportDryRun(t *testing.T) {
+	existing := `[{"uuid":"9d6a2c1e-8b1f-4c4e-9a55-1c2b3d4e5f60","description":"old","status":"pending","project":"Home"}]`
+	mock := &MockRunner{Output: existing}
+	common.Runner = mock
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{
+		"json_data": `{"uuid":"9d6a2c1e-8b1f-4c4e-9a55-1c2b3d4e5f60","description":"new","status":"pending"}
+{"description":"fresh"}`,
+		"dry_run": true,
+	}
+	res, err := importHandler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.False(t, res.IsError)
+
+	var report ImportReport
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(mcp.TextContent).Text), &report))
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 1, report.Created)
+	// Like task import, the payload replaces the task, so the project it leaves out goes.
+	assert.Equal(t, []FieldChange{{Field: "description", Old: "old", New: "new"}, {Field: "project", Old: "Home"}}, report.Items[0].Changes)
+	assert.NotContains(t, mock.LastArgs, "import")
+
+	// The real import passes the payload on as it is.
+	var imported []map[string]any
+	mock.Outputs = []string{existing, "Imported 2 tasks."}
+	mock.OnRun = func(args []string) {
+		if args[len(args)-2] == "import" {
+			data, err := os.ReadFile(args[len(args)-1])
+			assert.NoError(t, err)
+			assert.NoError(t, json.Unmarshal(data, &imported))
+		}
+	}
+	req.Params.Arguments.(map[string]any)["dry_run"] = false
+	res, err = importHandler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.False(t, res.IsError)
+	assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "Imported 2 tasks.")
+	assert.Equal(t, []map[string]any{
+		{"uuid": "9d6a2c1e-8b1f-4c4e-9a55-1c2b3d4e5f60", "description": "new", "status": "pending"},
+		{"description": "fresh"},
+	}, imported)
+
+	// With merge, the project is kept.
+	var merged []Task
+	mock.Outputs = []string{existing, "Imported 2 tasks."}
+	mock.OnRun = func(args []string) {
+		if args[len(args)-2] == "import" {
+			data, err := os.ReadFile(args[len(args)-1])
+			assert.NoError(t, err)
+			assert.NoError(t, json.Unmarshal(data, &merged))
+		}
+	}
+	req.Params.Arguments.(map[string]any)["merge"] = true
+	res, err = importHandler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.False(t, res.IsError)
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(mcp.TextContent).Text), &report))
+	assert.Equal(t, []FieldChange{{Field: "description", Old: "old", New: "new"}}, report.Items[0].Changes)
+	assert.Len(t, merged, 2)
+	assert.Equal(t, "new", merged[0].Description)
+	assert.Equal(t, "Home", merged[0].Project)
+	assert.Equal(t, "fresh", merged[1].Description)
+}
+
+func TestParseUDAs(t *testing.T) {
+	cfg := parseConfig("uda.estimate.type=numeric\nuda.estimate.label=Est\nuda.size.type=string\nuda.size.values=S,M,L,\nuda.reviewed.type=date\nreport.next.sort=urgency-")
+	udas := ParseUDAs(cfg)
+	assert.Len(t, udas, 3)
+	assert.Equal(t, UDA{Name: "estimate", Type: "numeric", Label: "Est"}, udas[0])
+	assert.Equal(t, "reviewed", udas[1].Name)
+	assert.Equal(t, []string{"S", "M", "L"}, udas[2].Values)
+
+	tool := addTool(udas)
+	props := tool.InputSchema.Properties["udas"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, "number", props["estimate"].(map[string]any)["type"])
+	assert.Equal(t, []string{"S", "M", "L"}, props["size"].(map[string]any)["enum"])
+}
+
+func TestExplainUrgency(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	task := Task{
+		UUID:     "a",
+		Status:   "pending",
+		Entry:    "20240110T120000Z",
+		Due:      "20240117T120000Z",
+		Priority: "H",
+		Project:  "Work",
+		Tags:     []string{"next"},
+		Urgency:  38.404,
+	}
+	pending := []Task{task, {UUID: "b", Status: "pending", Depends: []string{"a"}}}
+	cfg := map[string]string{"urgency.user.project.Work.coefficient": "2.0"}
+
+	b := ExplainUrgency(task, pending, cfg)
+	terms := map[string]float64{}
+	for _, term := range b.Terms {
+		terms[term.Name] = term.Contribution
+	}
+	assert.InDelta(t, 12.0*(7.0*0.8/21.0+0.2), terms["due"], 0.001)
+	assert.Equal(t, 6.0, terms["priority"])
+	assert.Equal(t, 0.8, terms["tags"])
+	assert.Equal(t, 1.0, terms["project"])
+	assert.Equal(t, 8.0, terms["blocking"])
+	assert.Equal(t, 15.0, terms["tag next"])
+	assert.Equal(t, 2.0, terms["project Work"])
+	assert.NotContains(t, terms, "blocked")
+	assert.InDelta(t, b.Exported, b.Computed, 0.01)
+}
+
+func TestBuildGraph(t *testing.T) {
+	tasks := []Task{
+		{UUID: "a", Description: "Ship", Status: "pending", Depends: []string{"b"}},
+		{UUID: "b", Description: "Test", Status: "pending", Depends: []string{"c"}},
+		{UUID: "c", Description: "Build", Status: "pending"},
+		{UUID: "d", Description: "Docs", Status: "pending", Depends: []string{"c"}},
+	}
+	g := BuildGraph(tasks, tasks)
+	assert.ElementsMatch(t, []string{"a", "b", "d"}, g.Blocked)
+	assert.Equal(t, []string{"c", "b", "a"}, g.CriticalPath)
+	assert.Equal(t, Unblocker{UUID: "c", Description: "Build", Unblocks: 3}, g.UnblocksMost[0])
+	assert.Empty(t, g.Cycles)
+	assert.Contains(t, g.Mermaid(), "tc --> tb")
+	assert.Contains(t, g.DOT(), "tc -> tb;")
+	assert.NotEqual(t, nodeID("9d6a2c1e-8b1f-4c4e-9a55-1c2b3d4e5f60"), nodeID("9d6a2c1e-0000-4c4e-9a55-1c2b3d4e5f60"))
+}
+
+func TestDependAddRejectsCycle(t *testing.T) {
+	mock := &MockRunner{Output: `[{"uuid":"a","status":"pending","depends":["b"]},{"uuid":"b","status":"pending"}]`}
+	common.Runner = mock
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"uuid": "b", "depends": []any{"a"}}
+
+	res, err := dependAddHandler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.True(t, res.IsError)
+	assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "cycle")
+	assert.Len(t, mock.Calls, 1)
+}
+
+func TestDependAddResolvesIDs(t *testing.T) {
+	export := `[{"id":1,"uuid":"9d6a2c1e-8b1f-4c4e-9a55-1c2b3d4e5f60","status":"pending","depends":["0b7e1d9a-6c4f-4e2b-8a3d-7f5e9c1b2a40"]},` +
+		`{"id":2,"uuid":"0b7e1d9a-6c4f-4e2b-8a3d-7f5e9c1b2a40","status":"pending"},` +
+		`{"id":3,"uuid":"0b7e1d9a-0000-4e2b-8a3d-7f5e9c1b2a41","status":"pending"}]`
+	for deps, want := range map[string]string{
+		"1":             "cycle",
+		"2":             "a task cannot depend on itself",
+		"0b7e1d9a-6c4f": "a task cannot depend on itself",
+		"0b7e1d9a":      `"0b7e1d9a" matches 2 tasks`,
+		"7":             `no task matches "7"`,
+	} {
+		mock := &MockRunner{Output: export}
+		common.Runner = mock
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = map[string]any{"uuid": "2", "depends": []any{deps}}
+		res, err := dependAddHandler(context.Background(), req)
+		assert.NoError(t, err)
+		assert.True(t, res.IsError, deps)
+		assert.Contains(t, res.Content[0].(mcp.TextContent).Text, want)
+		assert.Len(t, mock.Calls, 1)
+	}
+
+	mock := &MockRunner{Outputs: []string{export, "Modified 1 task."}}
+	common.Runner = mock
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"uuid": "3", "depends": []any{"1"}}
+	res, _ := dependAddHandler(context.Background(), req)
+	assert.False(t, res.IsError)
+	assert.Contains(t, mock.LastArgs, "0b7e1d9a-0000-4e2b-8a3d-7f5e9c1b2a41")
+	assert.Contains(t, mock.LastArgs, "depends:9d6a2c1e-8b1f-4c4e-9a55-1c2b3d4e5f60")
+}
+
+func TestValidRecurPeriod(t *testing.T) {
+	for _, p := range []string{"daily", "weekly", "2wks", "3d", "P1M", "P2W", "quarterly", "10 days"} {
+		assert.True(t, ValidRecurPeriod(p), p)
+	}
+	for _, p := range []string{"", "sometimes", "P", "2 fortnights x"} {
+		assert.False(t, ValidRecurPeriod(p), p)
+	}
+}
+
+func TestTaskRecurModifyPropagate(t *testing.T) {
+	mock := &MockRunner{Outputs: []string{"Modified 1 task.", "Modified 2 tasks."}}
+	common.Runner = mock
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"uuid": "tmpl", "modifications": "project:Chores", "propagate": true}
+
+	res, err := recurModifyHandler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.False(t, res.IsError)
+	assert.Len(t, mock.Calls, 2)
+	assert.Contains(t, strings.Join(mock.Calls[1], " "), "parent:tmpl ( status:pending or status:waiting )")
+	assert.Contains(t, mock.Calls[1], "project:Chores")
+	assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "Modified 2 tasks.")
+}
+
+func TestBuildReport(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	cfg := parseConfig(strings.Join([]string{
+		"report.next.columns=id,project,due.relative,description.count,urgency",
+		"report.next.labels=ID,Proj,Due,Description,Urg",
+		"report.next.filter=status:pending -WAITING",
+		"report.next.sort=urgency-",
+		"report.list.sort=project+,due+",
+	}, "\n"))
+	reports := ParseReports(cfg)
+	assert.Len(t, reports, 1)
+
+	tasks := []Task{
+		{ID: 1, Description: "Low", Urgency: 1.5},
+		{ID: 2, Description: "High", Project: "Work", Due: "20240112T120000Z", Urgency: 9.2,
+			Annotations: []Annotation{{Description: "note"}}},
+	}
+	result := BuildReport(reports[0], tasks, time.Local)
+	assert.Equal(t, ReportColumn{Name: "due.relative", Label: "Due"}, result.Columns[2])
+	assert.Equal(t, 2, result.Rows[0]["id"])
+	assert.Equal(t, "2d", result.Rows[0]["due.relative"])
+	assert.Equal(t, "High [1]", result.Rows[0]["description.count"])
+	assert.Nil(t, result.Rows[1]["project"])
+}
+
+func TestSortTasksMissingValuesLast(t *testing.T) {
+	tasks := []Task{{UUID: "a"}, {UUID: "b", Priority: "L"}, {UUID: "c", Priority: "H"}}
+	SortTasks(tasks, []string{"priority-"})
+	assert.Equal(t, "c", tasks[0].UUID)
+	assert.Equal(t, "b", tasks[1].UUID)
+	assert.Equal(t, "a", tasks[2].UUID)
+}
+
+func TestSortTasksMixedValues(t *testing.T) {
+	tasks := []Task{{UUID: "a", Project: "10"}, {UUID: "b", Project: "abc"}, {UUID: "c", Project: "9"}, {UUID: "d", Project: "Home"}, {UUID: "e", Project: "2"}}
+	order := func() string {
+		var ids []string
+		for _, t := range tasks {
+			ids = append(ids, t.UUID)
+		}
+		return strings.Join(ids, "")
+	}
+	SortTasks(tasks, []string{"project+"})
+	assert.Equal(t, "ecadb", order())
+	SortTasks(tasks, []string{"project-"})
+	assert.Equal(t, "acebd", order())
+}
+
+func TestAnalyticsIgnoresContext(t *testing.T) {
+	mock := &MockRunner{Output: "[]"}
+	common.Runner = mock
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"project": "Work"}
+	res, err := analyticsHandler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.False(t, res.IsError)
+	assert.Contains(t, mock.LastArgs, "rc.context=none")
+	assert.Contains(t, mock.LastArgs, "project:Work")
+}
+
+func TestComputeAnalytics(t *testing.T) {
+	tasks := []Task{
+		{Status: "completed", Project: "Work", Entry: "20240101T100000Z", End: "20240102T100000Z"},
+		{Status: "pending", Project: "Work", Entry: "20240101T120000Z", Due: "20240102T000000Z"},
+		{Status: "deleted", Project: "Home", Entry: "20240102T080000Z", End: "20240103T080000Z"},
+		{Status: "recurring", Project: "Home", Entry: "20240101T080000Z"},
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+
+	a := ComputeAnalytics(tasks, start, end, "day", "project")
+	assert.Len(t, a.Groups, 2)
+	home, work := a.Groups[0], a.Groups[1]
+	assert.Equal(t, "Work", work.Name)
+	assert.Len(t, work.Series, 3)
+	assert.Equal(t, Bucket{Start: "2024-01-01", Created: 2, Open: 2}, work.Series[0])
+	assert.Equal(t, Bucket{Start: "2024-01-02", Completed: 1, Open: 1, Overdue: 1}, work.Series[1])
+	assert.Equal(t, 24.0, *work.MedianLeadTimeHours)
+	assert.Equal(t, 1, home.Series[2].Deleted)
+	assert.Nil(t, home.MedianLeadTimeHours)
+}
+
+func TestTasksToICal(t *testing.T) {
+	ics := TasksToICal([]Task{{
+		UUID:        "a1",
+		Description: "Pay rent, today",
+		Status:      "pending",
+		Start:       "20240101T080000Z",
+		Due:         "20240101T120000Z",
+		Priority:    "H",
+		Tags:        []string{"home", "bills"},
+		Depends:     []string{"b2"},
+	}})
+	assert.Contains(t, ics, "BEGIN:VCALENDAR\r\n")
+	assert.Contains(t, ics, "BEGIN:VTODO\r\n")
+	assert.Contains(t, ics, "UID:a1\r\n")
+	assert.Contains(t, ics, "SUMMARY:Pay rent\\, today\r\n")
+	assert.Contains(t, ics, "STATUS:IN-PROCESS\r\n")
+	assert.Contains(t, ics, "DUE:20240101T120000Z\r\n")
+	assert.Contains(t, ics, "PRIORITY:1\r\n")
+	assert.Contains(t, ics, "CATEGORIES:home,bills\r\n")
+	assert.Contains(t, ics, "RELATED-TO;RELTYPE=DEPENDS-ON:b2\r\n")
+	assert.Contains(t, ics, "DTSTAMP:")
+
+	// DUE must be later than DTSTART, so a start after the due date is left out.
+	ics = TasksToICal([]Task{
+		{UUID: "a", Status: "pending", Scheduled: "20240101T080000Z", Due: "20240102T080000Z"},
+		{UUID: "b", Status: "pending", Scheduled: "20240103T080000Z", Due: "20240102T080000Z"},
+	})
+	assert.Equal(t, 1, strings.Count(ics, "DTSTART:"))
+	assert.Contains(t, ics, "DTSTART:20240101T080000Z\r\n")
+	assert.Equal(t, 2, strings.Count(ics, "DUE:20240102T080000Z"))
+}
+
+const sampleVTODO = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:todo-42@example.com\r\nSUMMARY:File taxes\r\nDESCRIPTION:Bring receipts\\, forms\r\nDUE;VALUE=DATE-TIME:20240415T170000Z\r\nPRIORITY:2\r\nCATEGORIES:finance,home office\r\nSTATUS:NEEDS-ACTION\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+
+func TestTaskFromVTODO(t *testing.T) {
+	components, err := common.ParseCalendar(sampleVTODO)
+	assert.NoError(t, err)
+	assert.Len(t, components, 1)
+
+	task, err := TaskFromVTODO(components[0], time.Local)
+	assert.NoError(t, err)
+	assert.Equal(t, StableUUID("todo-42@example.com"), task.UUID)
+	assert.Equal(t, "File taxes", task.Description)
+	assert.Equal(t, "20240415T170000Z", task.Due)
+	assert.Equal(t, "H", task.Priority)
+	assert.Equal(t, []string{"finance", "home_office"}, task.Tags)
+	assert.Equal(t, "Bring receipts, forms", task.Annotations[0].Description)
+	assert.Equal(t, "pending", task.Status)
+}
+
+func TestImportICalIdempotent(t *testing.T) {
+	components, _ := common.ParseCalendar(sampleVTODO)
+	task, _ := TaskFromVTODO(components[0], time.Local)
+	existing, _ := json.Marshal([]Task{task})
+
+	mock := &MockRunner{Output: string(existing)}
+	common.Runner = mock
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"ics_data": sampleVTODO}
+
+	res, err := importICalHandler(context.Background(), req)
+	assert.NoError(t, err)
+	var report ImportReport
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(mcp.TextContent).Text), &report))
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, 0, report.Created+report.Updated)
+	assert.Len(t, mock.Calls, 1) // only the lookup export, no import
+}
+
+func TestImportICalDryRunCreate(t *testing.T) {
+	mock := &MockRunner{Output: "[]"}
+	common.Runner = mock
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"ics_data": sampleVTODO, "dry_run": true}
+
+	res, err := importICalHandler(context.Background(), req)
+	assert.NoError(t, err)
+	var report ImportReport
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(mcp.TextContent).Text), &report))
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, "todo-42@example.com", report.Items[0].Source)
+	assert.Len(t, mock.Calls, 1)
+}
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	task, err := ParseTodoTxtLine("(A) 2024-01-01 Call mom +Family @phone due:2024-01-05", time.Local)
+	assert.NoError(t, err)
+	assert.Equal(t, "Call mom", task.Description)
+	assert.Equal(t, "H", task.Priority)
+	assert.Equal(t, "Family", task.Project)
+	assert.Equal(t, []string{"phone"}, task.Tags)
+	assert.Equal(t, "2024-01-05", localDay(task.Due, time.Local))
+	assert.Equal(t, "pending", task.Status)
+	assert.Equal(t, "(A) 2024-01-01 Call mom +Family @phone due:2024-01-05", TodoTxtLine(task, time.Local))
+
+	done, err := ParseTodoTxtLine("x 2024-01-03 2024-01-01 Call mom +Family pri:A", time.Local)
+	assert.NoError(t, err)
+	assert.Equal(t, "completed", done.Status)
+	assert.Equal(t, "2024-01-03", localDay(done.End, time.Local))
+	assert.Equal(t, task.UUID, done.UUID)
+	assert.Equal(t, "x 2024-01-03 2024-01-01 Call mom +Family pri:A", TodoTxtLine(done, time.Local))
+}
+
+func TestImportTodoTxtDryRun(t *testing.T) {
+	mock := &MockRunner{Output: "[]"}
+	common.Runner = mock
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"todo_txt": "(B) Water plants @home\n\nBuy milk +Errands\n", "dry_run": true}
+
+	res, err := importTodoTxtHandler(context.Background(), req)
+	assert.NoError(t, err)
+	var report ImportReport
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(mcp.TextContent).Text), &report))
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, "Buy milk +Errands", report.Items[1].Source)
+	assert.Len(t, mock.Calls, 1)
+}
+
+func TestRenderTasks(t *testing.T) {
+	tasks := []Task{
+		{ID: 2, Description: "Loose end", Status: "pending"},
+		{ID: 1, Description: "Write spec", Status: "pending", Project: "Work", Priority: "H",
+			Due: "20240105T120000Z", Tags: []string{"docs"},
+			Annotations: []Annotation{{Entry: "20240102T120000Z", Description: "draft shared"}}},
+		{Description: "Kickoff", Status: "completed", Project: "Work", End: "20240101T120000Z"},
+	}
+
+	md, err := RenderTasks(tasks, "markdown", "Context: none", time.Local)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(md, "_Context: none_\n\n## Work\n"))
+	assert.Contains(t, md, "- [ ] Write spec (due 2024-01-05, priority H) `#docs`\n  - 2024-01-02: draft shared\n")
+	assert.Contains(t, md, "- [x] Kickoff\n")
+	assert.Less(t, strings.Index(md, "## Work"), strings.Index(md, "## (no project)"))
+
+	org, err := RenderTasks(tasks, "org", "", time.Local)
+	assert.NoError(t, err)
+	assert.Contains(t, org, "** TODO [#A] Write spec :docs:\n   DEADLINE: <2024-01-05 Fri>\n")
+	assert.Contains(t, org, "** DONE Kickoff\n   CLOSED: [2024-01-01 Mon]\n")
+
+	csvOut, err := RenderTasks(tasks, "csv", "ignored", time.Local)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(csvOut, "id,uuid,status,project"))
+	assert.Contains(t, csvOut, "draft shared")
+
+	table, err := RenderTasks(tasks, "table", "", time.Local)
+	assert.NoError(t, err)
+	assert.Contains(t, table, "Write spec [1]")
+
+	_, err = RenderTasks(tasks, "yaml", "", time.Local)
+	assert.Error(t, err)
+}
+
+func TestTimezone(t *testing.T) {
+	var got *time.Location
+	handler := common.TimezoneMiddleware(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		got = common.Location(ctx)
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"timezone": "Asia/Tokyo"}
+	res, err := handler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.False(t, res.IsError)
+	assert.Equal(t, "Asia/Tokyo", got.String())
+
+	req.Params.Arguments = map[string]any{"timezone": "Mars/Olympus"}
+	res, _ = handler(context.Background(), req)
+	assert.True(t, res.IsError)
+
+	// 23:00 UTC is already the next day in Tokyo.
+	tasks := []Task{{ID: 1, Description: "Late", Status: "pending", Due: "20240105T230000Z"}}
+	md, err := RenderTasks(tasks, "markdown", "", got)
+	assert.NoError(t, err)
+	assert.Contains(t, md, "(due 2024-01-06)")
+	assert.Equal(t, "2024-01-06T08:00:00+09:00", ColumnValue(tasks[0], "due", got))
+
+	// The default JSON output carries the dates in the zone too.
+	common.Runner = &MockRunner{Output: `[{"id":1,"uuid":"a","description":"Late","status":"pending","due":"20240105T230000Z",` +
+		`"annotations":[{"entry":"20240105T120000Z","description":"note"}],"urgency":8.5}]`}
+	req.Params.Arguments = map[string]any{"ignore_context": true}
+	res, err = listHandler(common.WithLocation(context.Background(), got), req)
+	assert.NoError(t, err)
+	text := res.Content[0].(mcp.TextContent).Text
+	assert.Contains(t, text, `"due":"2024-01-06T08:00:00+09:00"`)
+	assert.Contains(t, text, `"entry":"2024-01-05T21:00:00+09:00"`)
+	assert.Contains(t, text, `"urgency":8.5`)
+}
+
+func TestProfiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+default_profile: personal
+profiles:
+  personal:
+    taskrc: /home/me/.taskrc
+  work:
+    taskrc: /srv/work/taskrc
+    taskdata: /srv/work/task
+    timewarriordb: /srv/work/timew
+`), 0o600))
+	cfg, err := common.LoadConfig(path)
+	assert.NoError(t, err)
+	assert.NoError(t, common.SetConfig(cfg))
+	defer common.SetConfig(common.DefaultConfig())
+
+	mock := &MockRunner{Output: "[]"}
+	common.Runner = mock
+	handler := common.ProfileMiddleware(listHandler)
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"ignore_context": true, "profile": "work"}
+	res, err := handler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.False(t, res.IsError)
+	assert.Equal(t, []string{"TASKRC=/srv/work/taskrc", "TASKDATA=/srv/work/task"}, mock.LastEnv)
+	work, err := common.LookupProfile("work")
+	assert.NoError(t, err)
+	timewEnv := common.TimewEnv(common.WithProfile(context.Background(), work))
+	assert.Equal(t, []string{"TIMEW_CONFIG=/srv/work/timew/timewarrior.cfg", "TIMEWARRIORDB=/srv/work/timew"}, timewEnv)
+
+	req.Params.Arguments = map[string]any{"ignore_context": true}
+	handler(context.Background(), req)
+	assert.Equal(t, []string{"TASKRC=/home/me/.taskrc"}, mock.LastEnv)
+
+	req.Params.Arguments = map[string]any{"profile": "play"}
+	res, _ = handler(context.Background(), req)
+	assert.True(t, res.IsError)
+	assert.Contains(t, res.Content[0].(mcp.TextContent).Text, "available profiles are personal, work")
+
+	assert.NoError(t, os.WriteFile(path, []byte("default_profile: nope\n"), 0o600))
+	_, err = common.LoadConfig(path)
+	assert.Error(t, err)
+}
+
+func TestUDAsPerProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+default_profile: personal
+profiles:
+  personal: {taskrc: /home/me/.taskrc}
+  work: {taskrc: /srv/work/taskrc}
+`), 0o600))
+	cfg, err := common.LoadConfig(path)
+	assert.NoError(t, err)
+	assert.NoError(t, common.SetConfig(cfg))
+	defer common.SetConfig(common.DefaultConfig())
+	udaState.byProfile = map[string]udaSet{}
+	defer func() { udaState.byProfile = map[string]udaSet{} }()
+
+	common.Runner = &MockRunner{Outputs: []string{
+		"uda.mood.type=string",
+		"uda.estimate.type=numeric\nuda.client.type=string\nuda.client.values=acme,globex",
+	}}
+	loadAllUDAs(context.Background())
+
+	// The schemas offer the UDAs of every profile.
+	props := addTool(allUDAs()).InputSchema.Properties["udas"].(map[string]any)["properties"].(map[string]any)
+	assert.Len(t, props, 3)
+	assert.Equal(t, []string{"acme", "globex"}, props["client"].(map[string]any)["enum"])
+
+	// Validation only accepts the UDAs of the profile the import runs in.
+	work, err := common.LookupProfile("work")
+	assert.NoError(t, err)
+	udas, err := knownUDAs(common.WithProfile(context.Background(), work))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"client", "estimate"}, []string{udas[0].Name, udas[1].Name})
+	udas, err = knownUDAs(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []UDA{{Name: "mood", Type: "string"}}, udas)
+}
+
+func TestConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+binaries: {task: /opt/task/bin/task}
+task:
+  overrides: [rc.hooks=off]
+  default_filter: status:pending -someday
+timeouts: {command: 30s}
+policy: {read_only: true}
+`), 0o600))
+	cfg, err := common.LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "timew", cfg.Binaries.Timew)
+	assert.Equal(t, 30*time.Second, time.Duration(cfg.Timeouts.Command))
+	assert.NoError(t, common.SetConfig(cfg))
+	defer common.SetConfig(common.DefaultConfig())
+
+	mock := &MockRunner{Output: "[]"}
+	common.Runner = mock
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"ignore_context": true}
+	_, err = listHandler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, "/opt/task/bin/task", mock.LastCmd)
+	assert.Equal(t, []string{"rc.confirmation=off", "rc.verbose=nothing", "rc.hooks=on", "rc.hooks=off",
+		"rc.context=none", "status:pending", "-someday", "export"}, mock.LastArgs)
+
+	tools := map[string]mcp.Tool{
+		"task_list":   mcp.NewTool("task_list", mcp.WithDescription("List tasks. NO CONFIRMATION NEEDED."), mcp.WithReadOnlyHintAnnotation(true)),
+		"task_delete": mcp.NewTool("task_delete", mcp.WithDescription("Delete a task. PROMPT FOR CONFIRMATION.")),
+	}
+	listed := common.PolicyFilter(context.Background(), []mcp.Tool{tools["task_list"], tools["task_delete"]})
+	assert.Len(t, listed, 1)
+	handler := common.PolicyMiddleware(func(name string) *server.ServerTool {
+		return &server.ServerTool{Tool: tools[name]}
+	})(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	req.Params.Name = "task_delete"
+	res, _ := handler(context.Background(), req)
+	assert.True(t, res.IsError)
+	req.Params.Name = "task_list"
+	res, _ = handler(context.Background(), req)
+	assert.False(t, res.IsError)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`
+transport: {type: pigeon}
+timeouts: {command: soon}
+`), 0o600))
+	_, err = common.LoadConfig(path)
+	assert.ErrorContains(t, err, `invalid duration "soon"`)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`
+transport: {type: http}
+task: {overrides: [hooks=off]}
+colour: true
+`), 0o600))
+	_, err = common.LoadConfig(path)
+	assert.ErrorContains(t, err, "field colour not found")
+
+	assert.NoError(t, os.WriteFile(path, []byte(`
+transport: {type: http}
+task: {overrides: [hooks=off]}
+`), 0o600))
+	_, err = common.LoadConfig(path)
+	assert.ErrorContains(t, err, "transport.address is required for the http transport")
+	assert.ErrorContains(t, err, `"hooks=off" is not of the form rc.<name>=<value>`)
+}
+
+func TestReminders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("reminders: {due: 2h, scheduled: 15m}\n"), 0o600))
+	cfg, err := common.LoadConfig(path)
+	assert.NoError(t, err)
+	assert.NoError(t, common.SetConfig(cfg))
+	defer common.SetConfig(common.DefaultConfig())
+	rc := CurrentReminderConfig()
+	assert.True(t, rc.Enabled)
+	assert.Equal(t, 2*time.Hour, rc.Due)
+	assert.Equal(t, 15*time.Minute, rc.Scheduled)
+	assert.Equal(t, time.Minute, rc.Interval)
+	assert.NoError(t, os.WriteFile(path, []byte("reminders: {due: soon}\n"), 0o600))
+	_, err = common.LoadConfig(path)
+	assert.Error(t, err)
+	assert.NoError(t, os.WriteFile(path, []byte("reminders: {interval: 0s}\n"), 0o600))
+	_, err = common.LoadConfig(path)
+	assert.ErrorContains(t, err, "reminders.interval must be at least 1s")
+
+	tasks := []Task{
+		{UUID: "a", Description: "Overdue", Status: "pending", Due: "20240110T110000Z"},
+		{UUID: "b", Description: "Soon", Status: "pending", Due: "20240110T133000Z"},
+		{UUID: "c", Description: "Later", Status: "pending", Due: "20240111T120000Z"},
+		{UUID: "d", Description: "Meeting prep", Status: "pending", Scheduled: "20240110T121400Z"},
+		{UUID: "e", Description: "Back", Status: "pending", Wait: "20240110T115900Z"},
+		{UUID: "f", Description: "Long ago", Status: "pending", Wait: "20240101T000000Z"},
+		{UUID: "g", Description: "Done", Status: "completed", Due: "20240101T000000Z"},
+	}
+	var sent []map[string]any
+	sched := &reminderScheduler{
+		notified: map[string]bool{},
+		since:    time.Date(2024, 1, 10, 11, 58, 0, 0, time.UTC),
+		notify: func(method string, params map[string]any) {
+			assert.Equal(t, "notifications/message", method)
+			sent = append(sent, params)
+		},
+	}
+	first := sched.tick(tasks, rc, time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC))
+	events := map[string]string{}
+	for _, r := range first {
+		events[r.UUID] = r.Event
+	}
+	assert.Equal(t, map[string]string{"a": "overdue", "b": "due_soon", "d": "scheduled", "e": "unwaited"}, events)
+	assert.Equal(t, "warning", sent[0]["level"])
+
+	// Nothing is sent twice, but a due date that passes is a new event.
+	second := sched.tick(tasks, rc, time.Date(2024, 1, 10, 13, 31, 0, 0, time.UTC))
+	assert.Len(t, second, 1)
+	assert.Equal(t, "b", second[0].UUID)
+	assert.Equal(t, "overdue", second[0].Event)
+	assert.Len(t, sent, 5)
+
+	// Reminders of finished tasks are forgotten.
+	sched.tick(tasks[:2], rc, time.Date(2024, 1, 10, 13, 32, 0, 0, time.UTC))
+	assert.Len(t, sched.notified, 2)
+
+	// At startup, the backlog is sent as one summary and not repeated.
+	sent = nil
+	sched = &reminderScheduler{notified: map[string]bool{}, catchUp: true, since: time.Date(2024, 1, 10, 11, 58, 0, 0, time.UTC),
+		notify: func(method string, params map[string]any) { sent = append(sent, params) }}
+	assert.Len(t, sched.tick(tasks, rc, time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)), 4)
+	assert.Len(t, sent, 1)
+	assert.Equal(t, "warning", sent[0]["level"])
+	assert.Equal(t, "summary", sent[0]["data"].(map[string]any)["event"])
+	assert.Empty(t, sched.tick(tasks, rc, time.Date(2024, 1, 10, 12, 1, 0, 0, time.UTC)))
+	assert.Len(t, sent, 1)
+}
+
+func TestRemindersIgnoreContext(t *testing.T) {
+	mock := &MockRunner{Output: "[]"}
+	common.Runner = mock
+	res, err := remindersHandler(context.Background(), mcp.CallToolRequest{})
+	assert.NoError(t, err)
+	assert.False(t, res.IsError)
+	assert.Contains(t, mock.LastArgs, "rc.context=none")
+}
+
+func TestNormalizeDateExpr(t *testing.T) {
+	cases := map[string]string{
+		"next Friday at 3pm": "friday+15h",
+		"tomorrow at 9:30":   "tomorrow+9h+30min",
+		"at noon":            "today+12h",
+		"in 10 days":         "now+10d",
+		"2 hours ago":        "now-2h",
+		"3 weeks from now":   "now+3w",
+		"end of month":       "eom",
+		"next week":          "sonw",
+		"eow":                "eow",
+		"2 wks":              "2wks",
+		"2024-05-01T17:00":   "2024-05-01T17:00",
+		"monday at 12:15 am": "monday+0h+15min",
+	}
+	for in, want := range cases {
+		got, err := NormalizeDateExpr(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	_, err := NormalizeDateExpr("friday at 25pm")
+	assert.Error(t, err)
+}
+
+func TestTaskResolveDate(t *testing.T) {
+	mock := &MockRunner{Outputs: []string{"P14D", "2024-01-24T10:00:00"}}
+	common.Runner = mock
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"expression": "2wks"}
+	res, err := resolveDateHandler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.False(t, res.IsError)
+	assert.Equal(t, []string{"calc", "2wks"}, mock.Calls[0][len(mock.Calls[0])-2:])
+	assert.Contains(t, mock.LastArgs, "now+2wks")
+
+	var resolved ResolvedDate
+	assert.NoError(t, json.Unmarshal([]byte(res.Content[0].(mcp.TextContent).Text), &resolved))
+	assert.Equal(t, "now+2wks", resolved.Expression)
+	assert.Equal(t, "2024-01-24T10:00:00", resolved.Taskwarrior)
+	assert.Equal(t, "Wednesday", resolved.Weekday)
+	want := time.Date(2024, 1, 24, 10, 0, 0, 0, time.Local)
+	assert.Equal(t, want.Format(time.RFC3339), resolved.ISO)
+	assert.Equal(t, want.UTC().Format(dateLayout), resolved.UTC)
+}
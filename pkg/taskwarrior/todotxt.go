@@ -0,0 +1,205 @@
+package taskwarrior
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const todoDateLayout = "2006-01-02"
+
+var (
+	todoPriority = regexp.MustCompile(`^\(([A-Z])\)$`)
+	todoDate     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// todoDay converts a todo.txt date into Taskwarrior's export format, at midnight in loc.
+func todoDay(s string, loc *time.Location) (string, error) {
+	t, err := time.ParseInLocation(todoDateLayout, s, loc)
+	if err != nil {
+		return "", err
+	}
+	return t.UTC().Format(dateLayout), nil
+}
+
+// localDay renders a Taskwarrior date as a todo.txt date in loc.
+func localDay(s string, loc *time.Location) string {
+	t, err := common.ParseDate(s)
+	if err != nil {
+		return ""
+	}
+	return t.In(loc).Format(todoDateLayout)
+}
+
+// ParseTodoTxtLine converts one todo.txt line into a Task, reading its dates
+// as days in loc. The UUID is derived
+// from the description and project, so re-importing the same item (even after
+// it has been marked done) updates the same task.
+func ParseTodoTxtLine(line string, loc *time.Location) (Task, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return Task{}, fmt.Errorf("empty line")
+	}
+	t := Task{Status: "pending"}
+
+	if fields[0] == "x" {
+		t.Status = "completed"
+		fields = fields[1:]
+		if len(fields) > 0 && todoDate.MatchString(fields[0]) {
+			end, err := todoDay(fields[0], loc)
+			if err != nil {
+				return Task{}, err
+			}
+			t.End = end
+			fields = fields[1:]
+		}
+	} else if len(fields) > 0 {
+		if m := todoPriority.FindStringSubmatch(fields[0]); m != nil {
+			t.Priority = todoToPriority(m[1])
+			fields = fields[1:]
+		}
+	}
+	if len(fields) > 0 && todoDate.MatchString(fields[0]) {
+		entry, err := todoDay(fields[0], loc)
+		if err != nil {
+			return Task{}, err
+		}
+		t.Entry = entry
+		fields = fields[1:]
+	}
+
+	var words []string
+	for _, f := range fields {
+		switch {
+		case strings.HasPrefix(f, "+") && len(f) > 1:
+			if t.Project == "" {
+				t.Project = f[1:]
+			} else {
+				t.Tags = append(t.Tags, f[1:])
+			}
+		case strings.HasPrefix(f, "@") && len(f) > 1:
+			t.Tags = append(t.Tags, f[1:])
+		case strings.HasPrefix(f, "due:") && todoDate.MatchString(f[4:]):
+			due, err := todoDay(f[4:], loc)
+			if err != nil {
+				return Task{}, err
+			}
+			t.Due = due
+		case strings.HasPrefix(f, "pri:") && len(f) == 5:
+			// Completed items conventionally keep their priority as pri:X.
+			t.Priority = todoToPriority(f[4:])
+		default:
+			words = append(words, f)
+		}
+	}
+	t.Description = strings.Join(words, " ")
+	if t.Description == "" {
+		return Task{}, fmt.Errorf("no description in %q", line)
+	}
+	if t.Status == "completed" && t.End == "" {
+		t.End = now().UTC().Format(dateLayout)
+	}
+	t.UUID = StableUUID("todo.txt:" + t.Project + ":" + t.Description)
+	return t, nil
+}
+
+func todoToPriority(letter string) string {
+	switch letter {
+	case "A":
+		return "H"
+	case "B":
+		return "M"
+	}
+	return "L"
+}
+
+var priorityToTodo = map[string]string{"H": "A", "M": "B", "L": "C"}
+
+// TodoTxtLine renders a task as a todo.txt line, with dates as days in loc.
+func TodoTxtLine(t Task, loc *time.Location) string {
+	var parts []string
+	if t.Status == "completed" {
+		parts = append(parts, "x")
+		if end := localDay(t.End, loc); end != "" {
+			parts = append(parts, end)
+		}
+	} else if p, ok := priorityToTodo[t.Priority]; ok {
+		parts = append(parts, "("+p+")")
+	}
+	if entry := localDay(t.Entry, loc); entry != "" {
+		parts = append(parts, entry)
+	}
+	parts = append(parts, t.Description)
+	if t.Project != "" {
+		parts = append(parts, "+"+strings.ReplaceAll(t.Project, " ", "_"))
+	}
+	for _, tag := range t.Tags {
+		parts = append(parts, "@"+tag)
+	}
+	if due := localDay(t.Due, loc); due != "" {
+		parts = append(parts, "due:"+due)
+	}
+	if p, ok := priorityToTodo[t.Priority]; ok && t.Status == "completed" {
+		parts = append(parts, "pri:"+p)
+	}
+	return strings.Join(parts, " ")
+}
+
+// ToTodoTxt renders tasks as a todo.txt file.
+func ToTodoTxt(tasks []Task, loc *time.Location) string {
+	var b strings.Builder
+	for _, t := range tasks {
+		b.WriteString(TodoTxtLine(t, loc))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func importTodoTxtHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	data, _ := argsMap["todo_txt"].(string)
+	dryRun, _ := argsMap["dry_run"].(bool)
+
+	var tasks []Task
+	var sources []string
+	for n, line := range strings.Split(data, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		t, err := ParseTodoTxtLine(line, common.Location(ctx))
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("line %d: %v", n+1, err)), nil
+		}
+		tasks = append(tasks, t)
+		sources = append(sources, strings.TrimSpace(line))
+	}
+	if len(tasks) == 0 {
+		return mcp.NewToolResultError("no todo.txt items found"), nil
+	}
+
+	// The item carries only some attributes: keep the others the task has.
+	report, err := runImport(ctx, tasks, sources, true, dryRun)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return common.JSONResult(report)
+}
+
+func exportTodoTxtHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	filter, _ := argsMap["filter"].(string)
+	if filter == "" {
+		filter = common.CurrentConfig().Task.DefaultFilter
+	}
+
+	tasks, err := ExportTasks(ctx, strings.Fields(filter)...)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(ToTodoTxt(tasks, common.Location(ctx))), nil
+}
@@ -0,0 +1,209 @@
+package taskwarrior
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// udaSet is the UDA configuration of one profile.
+type udaSet struct {
+	udas      []UDA
+	signature string
+}
+
+// udaState holds the UDA definitions of each profile, keyed by profile name,
+// that the add/modify schemas were last generated from.
+var udaState = struct {
+	sync.Mutex
+	byProfile map[string]udaSet
+}{byProfile: map[string]udaSet{}}
+
+// udaProperty converts a UDA definition into a JSON-schema property.
+func udaProperty(u UDA) map[string]any {
+	prop := map[string]any{}
+	desc := u.Label
+	if desc == "" {
+		desc = u.Name
+	}
+	switch u.Type {
+	case "numeric":
+		prop["type"] = "number"
+	case "date":
+		prop["type"] = "string"
+		desc += " (date, e.g. 'tomorrow' or '2024-05-01T17:00')"
+	case "duration":
+		prop["type"] = "string"
+		desc += " (duration, e.g. '2h' or 'P1D')"
+	default:
+		prop["type"] = "string"
+		if len(u.Values) > 0 {
+			prop["enum"] = u.Values
+		}
+	}
+	prop["description"] = desc
+	return prop
+}
+
+// udasOption builds the `udas` object property, typed from the configured UDAs.
+func udasOption(udas []UDA) mcp.ToolOption {
+	props := map[string]any{}
+	for _, u := range udas {
+		if coreAttributes[u.Name] {
+			continue
+		}
+		props[u.Name] = udaProperty(u)
+	}
+	return mcp.WithObject("udas",
+		mcp.Description("User Defined Attribute values keyed by UDA name"),
+		mcp.Properties(props),
+	)
+}
+
+func addTool(udas []UDA) mcp.Tool {
+	return mcp.NewTool("task_add",
+		mcp.WithDescription("Create a new task and return it as a JSON object. PROMPT FOR CONFIRMATION."),
+		common.WithTimezone(),
+		mcp.WithString("description", mcp.Required(), mcp.Description("Task description")),
+		mcp.WithString("project", mcp.Description("Project name")),
+		mcp.WithArray("tags", mcp.WithStringItems(), mcp.Description("Tags without the leading '+'")),
+		mcp.WithString("due", mcp.Description("Due date (e.g. 'tomorrow', '2024-05-01T17:00')")),
+		mcp.WithString("scheduled", mcp.Description("Scheduled date")),
+		mcp.WithString("priority", mcp.Enum("H", "M", "L"), mcp.Description("Priority")),
+		mcp.WithArray("depends", mcp.WithStringItems(), mcp.Description("UUIDs of tasks this task depends on")),
+		udasOption(udas),
+		mcp.WithString("metadata", mcp.Description("Additional attributes like 'project:Home due:2pm +next'")),
+	)
+}
+
+func modifyTool(udas []UDA) mcp.Tool {
+	return mcp.NewTool("task_modify",
+		mcp.WithDescription("Modify tasks. Can take filters and multiple modifications. PROMPT FOR CONFIRMATION."),
+		mcp.WithString("filter", mcp.Description("Filter for tasks to modify (e.g., '+PENDING project:Work')")),
+		mcp.WithString("modifications", mcp.Description("Modifications to apply (e.g., 'project:New /old/new/ +tag')")),
+		udasOption(udas),
+	)
+}
+
+// registerUDATools (re)registers the tools whose schemas depend on the configured UDAs.
+// Re-adding a tool makes the server emit notifications/tools/list_changed.
+func registerUDATools(s *server.MCPServer, udas []UDA) {
+	s.AddTools(
+		server.ServerTool{Tool: common.ProfileTool(addTool(udas)), Handler: addHandler},
+		server.ServerTool{Tool: common.ProfileTool(modifyTool(udas)), Handler: modifyHandler},
+	)
+}
+
+// loadUDAs reads the UDA configuration of the profile of ctx and reports
+// whether it changed since the last load.
+func loadUDAs(ctx context.Context) ([]UDA, bool, error) {
+	cfg, err := ShowConfig(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	udas := ParseUDAs(cfg)
+	sig, _ := json.Marshal(udas)
+
+	name := common.CurrentProfile(ctx).Name
+	udaState.Lock()
+	defer udaState.Unlock()
+	changed := string(sig) != udaState.byProfile[name].signature
+	udaState.byProfile[name] = udaSet{udas: udas, signature: string(sig)}
+	return udas, changed, nil
+}
+
+// allUDAs merges the loaded UDAs of every profile, since the add/modify
+// schemas are shared by all of them. When profiles define a UDA differently,
+// the profile whose name sorts first wins.
+func allUDAs() []UDA {
+	udaState.Lock()
+	defer udaState.Unlock()
+	names := make([]string, 0, len(udaState.byProfile))
+	for name := range udaState.byProfile {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	seen := map[string]bool{}
+	var udas []UDA
+	for _, name := range names {
+		for _, u := range udaState.byProfile[name].udas {
+			if !seen[u.Name] {
+				seen[u.Name] = true
+				udas = append(udas, u)
+			}
+		}
+	}
+	sort.Slice(udas, func(i, j int) bool { return udas[i].Name < udas[j].Name })
+	return udas
+}
+
+// watchedProfiles returns the profiles whose taskrc can be used: the
+// configured ones, plus the environment's paths when no default is set.
+func watchedProfiles() []common.Profile {
+	list, def := common.Profiles()
+	if def == "" {
+		list = append(list, common.Profile{})
+	}
+	return list
+}
+
+// loadAllUDAs reads the UDA configuration of every profile. A profile that
+// fails is logged and keeps its previous UDAs.
+func loadAllUDAs(ctx context.Context) {
+	for _, p := range watchedProfiles() {
+		if _, _, err := loadUDAs(common.WithProfile(ctx, p)); err != nil {
+			log.Printf("could not read UDA configuration%s: %v", profileSuffix(p), err)
+		}
+	}
+}
+
+// profileSuffix names a profile in log messages.
+func profileSuffix(p common.Profile) string {
+	if p.Name == "" {
+		return ""
+	}
+	return fmt.Sprintf(" of profile %q", p.Name)
+}
+
+// WatchUDAs polls the taskrc of every profile for changes and regenerates
+// the add/modify tool schemas whenever the configured UDAs change. Profiles
+// added by a config reload are picked up on the next poll.
+// It blocks, so run it in a goroutine.
+func WatchUDAs(s *server.MCPServer, interval time.Duration) {
+	ctx := context.Background()
+	lastMod := map[string]time.Time{}
+	for _, p := range watchedProfiles() {
+		if info, err := os.Stat(common.GetTaskrcPath(common.WithProfile(ctx, p))); err == nil {
+			lastMod[p.Name] = info.ModTime()
+		}
+	}
+	for range time.Tick(interval) {
+		changed := false
+		for _, p := range watchedProfiles() {
+			pctx := common.WithProfile(ctx, p)
+			info, err := os.Stat(common.GetTaskrcPath(pctx))
+			if err != nil || !info.ModTime().After(lastMod[p.Name]) {
+				continue
+			}
+			lastMod[p.Name] = info.ModTime()
+			_, c, err := loadUDAs(pctx)
+			if err != nil {
+				log.Printf("reloading UDAs%s: %v", profileSuffix(p), err)
+				continue
+			}
+			changed = changed || c
+		}
+		if changed {
+			registerUDATools(s, allUDAs())
+		}
+	}
+}
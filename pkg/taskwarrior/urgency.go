@@ -0,0 +1,271 @@
+package taskwarrior
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// now is the clock used for date dependent calculations; tests replace it.
+var now = time.Now
+
+// defaultUrgencyCoefficients mirrors Taskwarrior's built-in defaults and is
+// used for any coefficient missing from `task _show`.
+var defaultUrgencyCoefficients = map[string]float64{
+	"urgency.user.tag.next.coefficient":  15.0,
+	"urgency.due.coefficient":            12.0,
+	"urgency.blocking.coefficient":       8.0,
+	"urgency.uda.priority.H.coefficient": 6.0,
+	"urgency.uda.priority.M.coefficient": 3.9,
+	"urgency.uda.priority.L.coefficient": 1.8,
+	"urgency.scheduled.coefficient":      5.0,
+	"urgency.active.coefficient":         4.0,
+	"urgency.age.coefficient":            2.0,
+	"urgency.annotations.coefficient":    1.0,
+	"urgency.tags.coefficient":           1.0,
+	"urgency.project.coefficient":        1.0,
+	"urgency.waiting.coefficient":        -3.0,
+	"urgency.blocked.coefficient":        -5.0,
+}
+
+const defaultUrgencyAgeMax = 365.0
+
+// UrgencyTerm is one line of an urgency breakdown: contribution = factor * coefficient.
+type UrgencyTerm struct {
+	Name         string  `json:"name"`
+	Factor       float64 `json:"factor"`
+	Coefficient  float64 `json:"coefficient"`
+	Contribution float64 `json:"contribution"`
+	Detail       string  `json:"detail,omitempty"`
+}
+
+// UrgencyBreakdown explains how a task's urgency is composed.
+type UrgencyBreakdown struct {
+	UUID        string        `json:"uuid"`
+	Description string        `json:"description"`
+	Computed    float64       `json:"computed_urgency"`
+	Exported    float64       `json:"exported_urgency"`
+	Difference  float64       `json:"difference"`
+	Terms       []UrgencyTerm `json:"terms"`
+	Information string        `json:"information,omitempty"`
+}
+
+// urgencyCoefficients collects all urgency.* coefficients, applying defaults.
+func urgencyCoefficients(cfg map[string]string) map[string]float64 {
+	coeffs := map[string]float64{}
+	for k, v := range defaultUrgencyCoefficients {
+		coeffs[k] = v
+	}
+	for k, v := range cfg {
+		if !strings.HasPrefix(k, "urgency.") || !strings.HasSuffix(k, ".coefficient") {
+			continue
+		}
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			coeffs[k] = f
+		}
+	}
+	return coeffs
+}
+
+// countFactor scales tag and annotation counts the way Taskwarrior does.
+func countFactor(n int) float64 {
+	switch {
+	case n == 0:
+		return 0
+	case n == 1:
+		return 0.8
+	case n == 2:
+		return 0.9
+	}
+	return 1.0
+}
+
+// dueFactor maps the distance to the due date onto 0.2..1.0, reaching 1.0 a
+// week after the due date.
+func dueFactor(due, at time.Time) float64 {
+	daysOverdue := at.Sub(due).Hours() / 24
+	switch {
+	case daysOverdue >= 7.0:
+		return 1.0
+	case daysOverdue >= -14.0:
+		return ((daysOverdue+14.0)*0.8)/21.0 + 0.2
+	}
+	return 0.2
+}
+
+// ExplainUrgency computes the urgency of task term by term. pending holds the
+// unfinished tasks, used to determine blocked and blocking state.
+func ExplainUrgency(task Task, pending []Task, cfg map[string]string) UrgencyBreakdown {
+	coeffs := urgencyCoefficients(cfg)
+	at := now()
+	var terms []UrgencyTerm
+	add := func(name string, factor float64, coeffKey, detail string) {
+		c, ok := coeffs[coeffKey]
+		if !ok || factor == 0 || c == 0 {
+			return
+		}
+		terms = append(terms, UrgencyTerm{
+			Name:         name,
+			Factor:       round3(factor),
+			Coefficient:  c,
+			Contribution: round3(factor * c),
+			Detail:       detail,
+		})
+	}
+
+	if task.Due != "" {
+		if due, err := common.ParseDate(task.Due); err == nil {
+			add("due", dueFactor(due, at), "urgency.due.coefficient", "due "+due.Format(time.RFC3339))
+		}
+	}
+	if task.Priority != "" {
+		add("priority", 1.0, "urgency.uda.priority."+task.Priority+".coefficient", "priority:"+task.Priority)
+	}
+	if task.Start != "" {
+		add("active", 1.0, "urgency.active.coefficient", "task is started")
+	}
+	if task.Scheduled != "" {
+		if sched, err := common.ParseDate(task.Scheduled); err == nil && sched.Before(at) {
+			add("scheduled", 1.0, "urgency.scheduled.coefficient", "scheduled date has passed")
+		}
+	}
+	if task.Status == "waiting" {
+		add("waiting", 1.0, "urgency.waiting.coefficient", "task is waiting")
+	}
+	add("tags", countFactor(len(task.Tags)), "urgency.tags.coefficient", fmt.Sprintf("%d tags", len(task.Tags)))
+	add("annotations", countFactor(len(task.Annotations)), "urgency.annotations.coefficient", fmt.Sprintf("%d annotations", len(task.Annotations)))
+	if task.Project != "" {
+		add("project", 1.0, "urgency.project.coefficient", "project:"+task.Project)
+	}
+	if task.Entry != "" {
+		if entry, err := common.ParseDate(task.Entry); err == nil {
+			ageMax := defaultUrgencyAgeMax
+			if v, err := strconv.ParseFloat(cfg["urgency.age.max"], 64); err == nil {
+				ageMax = v
+			}
+			days := at.Sub(entry).Hours() / 24
+			factor := 1.0
+			if ageMax > 0 {
+				factor = math.Min(days/ageMax, 1.0)
+			}
+			add("age", factor, "urgency.age.coefficient", fmt.Sprintf("%.0f days old", days))
+		}
+	}
+
+	open := map[string]bool{}
+	for _, p := range pending {
+		open[p.UUID] = true
+	}
+	var blockers []string
+	for _, dep := range task.Depends {
+		if open[dep] {
+			blockers = append(blockers, dep)
+		}
+	}
+	if len(blockers) > 0 {
+		add("blocked", 1.0, "urgency.blocked.coefficient", "waiting on "+strings.Join(blockers, ","))
+	}
+	var blocked []string
+	for _, p := range pending {
+		for _, dep := range p.Depends {
+			if dep == task.UUID {
+				blocked = append(blocked, p.UUID)
+			}
+		}
+	}
+	if len(blocked) > 0 {
+		add("blocking", 1.0, "urgency.blocking.coefficient", "blocks "+strings.Join(blocked, ","))
+	}
+
+	// User defined coefficients, in a stable order.
+	keys := make([]string, 0, len(coeffs))
+	for k := range coeffs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := strings.TrimSuffix(k, ".coefficient")
+		switch {
+		case strings.HasPrefix(name, "urgency.user.tag."):
+			tag := strings.TrimPrefix(name, "urgency.user.tag.")
+			if task.HasTag(tag) {
+				add("tag "+tag, 1.0, k, "+"+tag)
+			}
+		case strings.HasPrefix(name, "urgency.user.project."):
+			project := strings.TrimPrefix(name, "urgency.user.project.")
+			if task.Project != "" && strings.HasPrefix(task.Project, project) {
+				add("project "+project, 1.0, k, "project:"+task.Project)
+			}
+		case strings.HasPrefix(name, "urgency.uda."):
+			uda := strings.TrimPrefix(name, "urgency.uda.")
+			if strings.HasPrefix(uda, "priority.") {
+				continue
+			}
+			if udaName, value, ok := strings.Cut(uda, "."); ok {
+				if v, set := task.UDA[udaName]; set && fmt.Sprint(v) == value {
+					add("uda "+udaName, 1.0, k, udaName+":"+value)
+				}
+			} else if v, set := task.UDA[uda]; set && fmt.Sprint(v) != "" {
+				add("uda "+uda, 1.0, k, fmt.Sprintf("%s:%v", uda, v))
+			}
+		}
+	}
+
+	var total float64
+	for _, term := range terms {
+		total += term.Factor * term.Coefficient
+	}
+	return UrgencyBreakdown{
+		UUID:        task.UUID,
+		Description: task.Description,
+		Computed:    round3(total),
+		Exported:    task.Urgency,
+		Difference:  round3(task.Urgency - total),
+		Terms:       terms,
+	}
+}
+
+func round3(f float64) float64 {
+	return math.Round(f*1000) / 1000
+}
+
+func explainUrgencyHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	uuid, _ := argsMap["uuid"].(string)
+	withInfo, _ := argsMap["include_information"].(bool)
+
+	tasks, err := ExportTasks(ctx, uuid)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(tasks) != 1 {
+		return mcp.NewToolResultError(fmt.Sprintf("expected exactly one task for %q, found %d", uuid, len(tasks))), nil
+	}
+	pending, err := exportOpen(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	cfg, err := ShowConfig(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	breakdown := ExplainUrgency(tasks[0], pending, cfg)
+	if withInfo {
+		cmd := &TaskCommand{
+			Filters: []string{uuid},
+			Command: "information",
+		}
+		if info, err := cmd.Run(ctx); err == nil {
+			breakdown.Information = info
+		}
+	}
+	return common.LocalJSONResult(ctx, breakdown)
+}
@@ -0,0 +1,201 @@
+package taskwarrior
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"warmcp/pkg/common"
+)
+
+// ValidationIssue is a problem found in one task of an import payload.
+type ValidationIssue struct {
+	Index   int    `json:"index"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+func (v ValidationIssue) String() string {
+	if v.Field == "" {
+		return fmt.Sprintf("task %d: %s", v.Index, v.Message)
+	}
+	return fmt.Sprintf("task %d: %s: %s", v.Index, v.Field, v.Message)
+}
+
+var validStatuses = map[string]bool{
+	"pending": true, "completed": true, "deleted": true, "waiting": true, "recurring": true,
+}
+
+// importDateLayouts are the date formats `task import` accepts.
+var importDateLayouts = []string{dateLayout, time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+func validDate(v any) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	for _, layout := range importDateLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinAttributes lists what Taskwarrior writes beyond the Task fields,
+// such as the bookkeeping of recurring tasks, so that its own exports import
+// again. They are passed through unchecked.
+var builtinAttributes = map[string]bool{"rtype": true, "template": true, "last": true, "recurrence": true}
+
+// knownUDAs returns the UDAs configured in the profile of ctx, reading the
+// configuration if it has not been loaded yet.
+func knownUDAs(ctx context.Context) ([]UDA, error) {
+	udaState.Lock()
+	set, loaded := udaState.byProfile[common.CurrentProfile(ctx).Name]
+	udaState.Unlock()
+	if loaded {
+		return set.udas, nil
+	}
+	udas, _, err := loadUDAs(ctx)
+	return udas, err
+}
+
+// hasUDAKeys reports whether any object carries a non-core attribute, which
+// is the only case where validation needs the UDA configuration.
+func hasUDAKeys(objects []map[string]any) bool {
+	for _, obj := range objects {
+		for key := range obj {
+			if !coreAttributes[key] && !builtinAttributes[key] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeImport accepts a JSON array, a single object or one object per line,
+// like `task import` does.
+func decodeImport(data string) ([]map[string]any, error) {
+	data = strings.TrimSpace(data)
+	var objects []map[string]any
+	if strings.HasPrefix(data, "[") {
+		if err := json.Unmarshal([]byte(data), &objects); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+		return objects, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader([]byte(data)))
+	for dec.More() {
+		var obj map[string]any
+		if err := dec.Decode(&obj); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// ValidateImport checks decoded import objects against the Task schema and the
+// configured UDAs, returning the tasks and any issues found.
+func ValidateImport(objects []map[string]any, udas []UDA) ([]Task, []ValidationIssue) {
+	udaByName := map[string]UDA{}
+	for _, u := range udas {
+		udaByName[u.Name] = u
+	}
+
+	var tasks []Task
+	issues := []ValidationIssue{}
+	for i, obj := range objects {
+		report := func(field, format string, args ...any) {
+			issues = append(issues, ValidationIssue{Index: i, Field: field, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if desc, ok := obj["description"].(string); !ok || strings.TrimSpace(desc) == "" {
+			report("description", "is required")
+		}
+		if status, ok := obj["status"]; ok {
+			if s, _ := status.(string); !validStatuses[s] {
+				report("status", "invalid value %v", status)
+			}
+		}
+		if uuid, ok := obj["uuid"]; ok {
+			if s, _ := uuid.(string); !uuidPattern.MatchString(s) {
+				report("uuid", "%v is not a UUID", uuid)
+			}
+		}
+		for field := range dateAttributes {
+			if v, ok := obj[field]; ok && !validDate(v) {
+				report(field, "invalid date %v", v)
+			}
+		}
+		if anns, ok := obj["annotations"].([]any); ok {
+			for n, a := range anns {
+				ann, _ := a.(map[string]any)
+				if entry, ok := ann["entry"]; ok && !validDate(entry) {
+					report(fmt.Sprintf("annotations[%d].entry", n), "invalid date %v", entry)
+				}
+			}
+		}
+		if deps, ok := obj["depends"]; ok {
+			var list []string
+			switch d := deps.(type) {
+			case string:
+				list = splitList(d)
+			case []any:
+				for _, item := range d {
+					s, _ := item.(string)
+					list = append(list, s)
+				}
+			}
+			for _, dep := range list {
+				if !uuidPattern.MatchString(dep) {
+					report("depends", "%q is not a UUID", dep)
+				}
+			}
+		}
+
+		for key, val := range obj {
+			if coreAttributes[key] || builtinAttributes[key] {
+				continue
+			}
+			u, ok := udaByName[key]
+			if !ok {
+				report(key, "unknown attribute (not a configured UDA)")
+				continue
+			}
+			switch u.Type {
+			case "numeric":
+				if _, ok := val.(float64); !ok {
+					report(key, "expected a number, got %v", val)
+				}
+			case "date":
+				if !validDate(val) {
+					report(key, "invalid date %v", val)
+				}
+			default:
+				if len(u.Values) > 0 && !contains(u.Values, fmt.Sprint(val)) {
+					report(key, "%v is not one of %s", val, strings.Join(u.Values, ","))
+				}
+			}
+		}
+
+		raw, _ := json.Marshal(obj)
+		var t Task
+		if err := json.Unmarshal(raw, &t); err != nil {
+			report("", "%v", err)
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, issues
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
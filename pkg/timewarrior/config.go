@@ -0,0 +1,64 @@
+package timewarrior
+
+import (
+	"context"
+	"os"
+	"strings"
+	"warmcp/pkg/common"
+)
+
+// ParseConfig flattens a timewarrior.cfg file into dotted keys. Both the
+// "a.b = c" form and indented "a:" blocks are understood; import lines and
+// comments are skipped.
+func ParseConfig(text string) map[string]string {
+	cfg := map[string]string{}
+	type block struct {
+		indent int
+		name   string
+	}
+	var stack []block
+	for _, raw := range strings.Split(text, "\n") {
+		line := raw
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		line = strings.TrimSpace(line)
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		if strings.HasPrefix(line, "import ") {
+			continue
+		}
+		prefix := ""
+		for _, b := range stack {
+			prefix += b.name + "."
+		}
+		if key, val, ok := strings.Cut(line, "="); ok {
+			cfg[prefix+strings.TrimSpace(key)] = strings.TrimSpace(val)
+			continue
+		}
+		if name, ok := strings.CutSuffix(line, ":"); ok {
+			// "define exclusions:" opens the same namespace as "exclusions.".
+			name = strings.TrimPrefix(strings.TrimSpace(name), "define ")
+			stack = append(stack, block{indent: indent, name: strings.TrimSpace(name)})
+		}
+	}
+	return cfg
+}
+
+// LoadConfig reads and parses the Timewarrior configuration file. A missing
+// file yields an empty configuration, as Timewarrior itself treats it.
+func LoadConfig(ctx context.Context) (map[string]string, error) {
+	data, err := os.ReadFile(common.GetTimewConfigPath(ctx))
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(string(data)), nil
+}
@@ -0,0 +1,470 @@
+package timewarrior
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// span is a part of a day, as offsets from local midnight.
+type span struct {
+	from, to time.Duration
+}
+
+// Exclusions are the times Timewarrior considers outside working hours, read
+// from the exclusions.* and holidays.* configuration keys.
+type Exclusions struct {
+	Weekly   map[time.Weekday][]span
+	Days     map[string][]span
+	Holidays map[string]bool
+	// Default is set when nothing is configured and Monday to Friday,
+	// 09:00 to 17:00, is assumed instead.
+	Default bool
+}
+
+const fullDay = 24 * time.Hour
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// parseClock parses "8:00" or "17:30:15" into an offset from midnight.
+func parseClock(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	var d time.Duration
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+		d += time.Duration(n) * units[i]
+	}
+	if d > fullDay {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return d, nil
+}
+
+// parseExclusionSpec parses a value such as "<8:00 12:00-12:45 >17:30".
+func parseExclusionSpec(spec string) ([]span, error) {
+	var spans []span
+	for _, tok := range strings.Fields(spec) {
+		switch {
+		case strings.HasPrefix(tok, "<"):
+			t, err := parseClock(tok[1:])
+			if err != nil {
+				return nil, err
+			}
+			spans = append(spans, span{0, t})
+		case strings.HasPrefix(tok, ">"):
+			t, err := parseClock(tok[1:])
+			if err != nil {
+				return nil, err
+			}
+			spans = append(spans, span{t, fullDay})
+		default:
+			from, to, ok := strings.Cut(tok, "-")
+			if !ok {
+				return nil, fmt.Errorf("invalid exclusion %q", tok)
+			}
+			f, err := parseClock(from)
+			if err != nil {
+				return nil, err
+			}
+			t, err := parseClock(to)
+			if err != nil {
+				return nil, err
+			}
+			spans = append(spans, span{f, t})
+		}
+	}
+	return spans, nil
+}
+
+// configDate converts Timewarrior's 2024_12_24 key form into 2024-12-24.
+func configDate(key string) (string, bool) {
+	date := strings.ReplaceAll(key, "_", "-")
+	if _, err := time.Parse(common.DayLayout, date); err != nil {
+		return "", false
+	}
+	return date, true
+}
+
+// ParseExclusions reads working hours from a Timewarrior configuration.
+// exclusions.days.<date> accepts "off" for a day off, "on" for a working day
+// without exclusions, or a spec that replaces the weekday's exclusions.
+func ParseExclusions(cfg map[string]string) (Exclusions, error) {
+	ex := Exclusions{Weekly: map[time.Weekday][]span{}, Days: map[string][]span{}, Holidays: map[string]bool{}}
+	configured := false
+	for key, val := range cfg {
+		if rest, ok := strings.CutPrefix(key, "holidays."); ok {
+			if _, day, ok := strings.Cut(rest, "."); ok {
+				if date, ok := configDate(day); ok {
+					ex.Holidays[date] = true
+				}
+			}
+			continue
+		}
+		rest, ok := strings.CutPrefix(key, "exclusions.")
+		if !ok {
+			continue
+		}
+		configured = true
+		if day, ok := strings.CutPrefix(rest, "days."); ok {
+			date, ok := configDate(day)
+			if !ok {
+				return Exclusions{}, fmt.Errorf("invalid date in %s", key)
+			}
+			switch val {
+			case "off":
+				ex.Days[date] = []span{{0, fullDay}}
+			case "on":
+				ex.Days[date] = []span{}
+			default:
+				spans, err := parseExclusionSpec(val)
+				if err != nil {
+					return Exclusions{}, fmt.Errorf("%s: %v", key, err)
+				}
+				ex.Days[date] = spans
+			}
+			continue
+		}
+		wd, ok := weekdays[rest]
+		if !ok {
+			continue
+		}
+		spans, err := parseExclusionSpec(val)
+		if err != nil {
+			return Exclusions{}, fmt.Errorf("%s: %v", key, err)
+		}
+		ex.Weekly[wd] = spans
+	}
+	if !configured {
+		ex.Default = true
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			if wd == time.Saturday || wd == time.Sunday {
+				ex.Weekly[wd] = []span{{0, fullDay}}
+			} else {
+				ex.Weekly[wd] = []span{{0, 9 * time.Hour}, {17 * time.Hour, fullDay}}
+			}
+		}
+	}
+	return ex, nil
+}
+
+// period is an absolute time range.
+type period struct {
+	start, end time.Time
+}
+
+// WorkingPeriods returns the working time of the day starting at day, in its timezone.
+func (ex Exclusions) WorkingPeriods(day time.Time) []period {
+	date := day.Format(common.DayLayout)
+	if ex.Holidays[date] {
+		return nil
+	}
+	excluded, ok := ex.Days[date]
+	if !ok {
+		excluded = ex.Weekly[day.Weekday()]
+	}
+	excluded = append([]span(nil), excluded...)
+	sort.Slice(excluded, func(i, j int) bool { return excluded[i].from < excluded[j].from })
+
+	// Wall-clock arithmetic keeps the offsets right on DST changeover days.
+	at := func(d time.Duration) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, int(d/time.Second), 0, day.Location())
+	}
+	var periods []period
+	cursor := time.Duration(0)
+	for _, s := range excluded {
+		if s.from > cursor {
+			periods = append(periods, period{at(cursor), at(s.from)})
+		}
+		if s.to > cursor {
+			cursor = s.to
+		}
+	}
+	if cursor < fullDay {
+		periods = append(periods, period{at(cursor), at(fullDay)})
+	}
+	return periods
+}
+
+// mergePeriods sorts and joins overlapping or touching periods.
+func mergePeriods(periods []period) []period {
+	sort.Slice(periods, func(i, j int) bool { return periods[i].start.Before(periods[j].start) })
+	var merged []period
+	for _, p := range periods {
+		if n := len(merged); n > 0 && !p.start.After(merged[n-1].end) {
+			if p.end.After(merged[n-1].end) {
+				merged[n-1].end = p.end
+			}
+			continue
+		}
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// subtractPeriods returns the parts of window not covered by the merged periods.
+func subtractPeriods(window period, covered []period) []period {
+	var free []period
+	cursor := window.start
+	for _, c := range covered {
+		if !c.end.After(cursor) || !c.start.Before(window.end) {
+			continue
+		}
+		if c.start.After(cursor) {
+			free = append(free, period{cursor, c.start})
+		}
+		cursor = c.end
+	}
+	if window.end.After(cursor) {
+		free = append(free, period{cursor, window.end})
+	}
+	return free
+}
+
+// Gap is untracked working time.
+type Gap struct {
+	Start      string `json:"start"`
+	End        string `json:"end"`
+	Minutes    int    `json:"minutes"`
+	Suggestion string `json:"suggestion"`
+}
+
+// Overlap is time tracked by two intervals at once.
+type Overlap struct {
+	First   int    `json:"first_id"`
+	Second  int    `json:"second_id"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	Minutes int    `json:"minutes"`
+}
+
+// FlaggedInterval is an interval that needs attention.
+type FlaggedInterval struct {
+	ID         int      `json:"id"`
+	Start      string   `json:"start"`
+	End        string   `json:"end,omitempty"`
+	Hours      float64  `json:"hours"`
+	Tags       []string `json:"tags,omitempty"`
+	Suggestion string   `json:"suggestion"`
+}
+
+// GapReport is the result of checking a range of tracked time for problems.
+type GapReport struct {
+	Start          string            `json:"start"`
+	End            string            `json:"end"`
+	WorkingHours   string            `json:"working_hours"`
+	WorkingMinutes int               `json:"working_minutes"`
+	TrackedMinutes int               `json:"tracked_working_minutes"`
+	Gaps           []Gap             `json:"gaps"`
+	Overlaps       []Overlap         `json:"overlaps"`
+	LongOpen       []FlaggedInterval `json:"long_open"`
+	Untagged       []FlaggedInterval `json:"untagged"`
+	Suggestions    []string          `json:"suggestions"`
+}
+
+func localTime(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(common.LocalLayout)
+}
+
+func quoteTags(tags []string) string {
+	quoted := make([]string, len(tags))
+	for i, tag := range tags {
+		if strings.ContainsAny(tag, " \t\"'") {
+			tag = strconv.Quote(tag)
+		}
+		quoted[i] = tag
+	}
+	return strings.Join(quoted, " ")
+}
+
+// FindGaps checks the intervals between the days first and last (inclusive)
+// against the working hours, in the timezone of first. Gaps shorter than
+// minGap are ignored, and open intervals running longer than maxOpen are flagged.
+func FindGaps(intervals []Interval, ex Exclusions, first, last time.Time, minGap, maxOpen time.Duration) GapReport {
+	report := GapReport{
+		Start:        first.Format(common.DayLayout),
+		End:          last.Format(common.DayLayout),
+		WorkingHours: "timewarrior exclusions",
+		Gaps:         []Gap{},
+		Overlaps:     []Overlap{},
+		LongOpen:     []FlaggedInterval{},
+		Untagged:     []FlaggedInterval{},
+		Suggestions:  []string{},
+	}
+	if ex.Default {
+		report.WorkingHours = "default (Monday to Friday, 09:00-17:00); configure exclusions in timewarrior.cfg"
+	}
+	limit := now()
+	loc := first.Location()
+
+	type timed struct {
+		Interval
+		start, end time.Time
+	}
+	var items []timed
+	var covered []period
+	for _, i := range intervals {
+		s, e, err := i.Times()
+		if err != nil {
+			continue
+		}
+		items = append(items, timed{i, s, e})
+		covered = append(covered, period{s, e})
+	}
+	covered = mergePeriods(covered)
+	sort.SliceStable(items, func(a, b int) bool { return items[a].start.Before(items[b].start) })
+
+	// tagsBefore finds the tags of the interval that ended last before t, to
+	// prefill gap suggestions.
+	tagsBefore := func(t time.Time) []string {
+		var tags []string
+		var best time.Time
+		for _, it := range items {
+			if !it.end.After(t) && it.end.After(best) && len(it.Tags) > 0 {
+				best, tags = it.end, it.Tags
+			}
+		}
+		return tags
+	}
+
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		for _, w := range ex.WorkingPeriods(day) {
+			if w.end.After(limit) {
+				w.end = limit
+			}
+			if !w.end.After(w.start) {
+				continue
+			}
+			report.WorkingMinutes += int(w.end.Sub(w.start).Minutes())
+			free := subtractPeriods(w, covered)
+			untracked := time.Duration(0)
+			for _, f := range free {
+				untracked += f.end.Sub(f.start)
+				if f.end.Sub(f.start) < minGap {
+					continue
+				}
+				cmd := fmt.Sprintf("timew track %s - %s", common.CommandDate(f.start), common.CommandDate(f.end))
+				if tags := tagsBefore(f.start); len(tags) > 0 {
+					cmd += " " + quoteTags(tags)
+				}
+				report.Gaps = append(report.Gaps, Gap{
+					Start:      localTime(f.start, loc),
+					End:        localTime(f.end, loc),
+					Minutes:    int(f.end.Sub(f.start).Minutes()),
+					Suggestion: cmd,
+				})
+				report.Suggestions = append(report.Suggestions, cmd)
+			}
+			report.TrackedMinutes += int((w.end.Sub(w.start) - untracked).Minutes())
+		}
+	}
+
+	for a := range items {
+		for b := a + 1; b < len(items) && items[b].start.Before(items[a].end); b++ {
+			end := items[a].end
+			if items[b].end.Before(end) {
+				end = items[b].end
+			}
+			report.Overlaps = append(report.Overlaps, Overlap{
+				First:   items[a].ID,
+				Second:  items[b].ID,
+				Start:   localTime(items[b].start, loc),
+				End:     localTime(end, loc),
+				Minutes: int(end.Sub(items[b].start).Minutes()),
+			})
+		}
+	}
+
+	for _, it := range items {
+		flagged := FlaggedInterval{
+			ID:    it.ID,
+			Start: localTime(it.start, loc),
+			Hours: hours(it.end.Sub(it.start)),
+			Tags:  it.Tags,
+		}
+		if !it.Open() {
+			flagged.End = localTime(it.end, loc)
+		}
+		if it.Open() && it.end.Sub(it.start) > maxOpen {
+			flagged.Suggestion = "timew stop"
+			report.LongOpen = append(report.LongOpen, flagged)
+			report.Suggestions = append(report.Suggestions, flagged.Suggestion)
+		}
+		if len(it.Tags) == 0 {
+			flagged.Suggestion = fmt.Sprintf("timew tag @%d <tags>", it.ID)
+			report.Untagged = append(report.Untagged, flagged)
+			report.Suggestions = append(report.Suggestions, flagged.Suggestion)
+		}
+	}
+	return report
+}
+
+func gapsHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	startArg, _ := argsMap["start"].(string)
+	endArg, _ := argsMap["end"].(string)
+
+	loc := common.Location(ctx)
+	last := common.StartOfDay(now(), loc)
+	first := last.AddDate(0, 0, -6)
+	if startArg != "" {
+		t, err := common.ParseDay(startArg, loc)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		first = t
+	}
+	if endArg != "" {
+		t, err := common.ParseDay(endArg, loc)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		last = t
+	}
+	if last.Before(first) {
+		return mcp.NewToolResultError("end must not be before start"), nil
+	}
+	minGap := 15 * time.Minute
+	if v, ok := argsMap["min_gap"].(float64); ok {
+		minGap = time.Duration(v * float64(time.Minute))
+	}
+	maxOpen := 8 * time.Hour
+	if v, ok := argsMap["max_open"].(float64); ok {
+		maxOpen = time.Duration(v * float64(time.Hour))
+	}
+
+	cfg, err := LoadConfig(ctx)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	ex, err := ParseExclusions(cfg)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	intervals, err := ExportBetween(ctx, first, last.AddDate(0, 0, 1))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	data, err := json.MarshalIndent(FindGaps(intervals, ex, first, last, minGap, maxOpen), "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -0,0 +1,90 @@
+package timewarrior
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// defaultCalendarRange is the range published on timew://calendar.ics.
+const defaultCalendarRange = ":week"
+
+// VEVENT converts a tracked interval into an RFC 5545 VEVENT component.
+// Open intervals end at the current time.
+func VEVENT(i Interval) common.ICalComponent {
+	c := common.ICalComponent{Name: "VEVENT"}
+	c.Add("UID", i.Start+"@timewarrior")
+	start, end, err := i.Times()
+	if err != nil {
+		return c
+	}
+	c.Add("DTSTART", start.UTC().Format(common.ICalDateLayout))
+	c.Add("DTEND", end.UTC().Format(common.ICalDateLayout))
+
+	summary := strings.Join(i.Tags, " ")
+	if summary == "" {
+		summary = "(untagged)"
+	}
+	c.AddText("SUMMARY", summary)
+	if i.Annotation != "" {
+		c.AddText("DESCRIPTION", i.Annotation)
+	}
+	if len(i.Tags) > 0 {
+		escaped := make([]string, len(i.Tags))
+		for n, tag := range i.Tags {
+			escaped[n] = common.ICalEscape(tag)
+		}
+		c.Add("CATEGORIES", strings.Join(escaped, ","))
+	}
+	if i.Open() {
+		c.Add("STATUS", "TENTATIVE")
+	} else {
+		c.Add("STATUS", "CONFIRMED")
+	}
+	return c
+}
+
+// IntervalsToICal renders intervals as a VCALENDAR of VEVENTs.
+func IntervalsToICal(intervals []Interval) string {
+	components := make([]common.ICalComponent, len(intervals))
+	for n, i := range intervals {
+		components[n] = VEVENT(i)
+	}
+	return common.WriteCalendar(components)
+}
+
+func exportICalHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	trange, _ := argsMap["range"].(string)
+	if trange == "" {
+		trange = defaultCalendarRange
+	}
+	intervals, err := ExportIntervals(ctx, strings.Fields(trange)...)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(IntervalsToICal(intervals)), nil
+}
+
+func calendarResourceHandler(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	trange := defaultCalendarRange
+	if u, err := url.Parse(req.Params.URI); err == nil {
+		if r := u.Query().Get("range"); r != "" {
+			trange = r
+		}
+	}
+	intervals, err := ExportIntervals(ctx, strings.Fields(trange)...)
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      req.Params.URI,
+			MIMEType: "text/calendar",
+			Text:     IntervalsToICal(intervals),
+		},
+	}, nil
+}
@@ -0,0 +1,78 @@
+package timewarrior
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"warmcp/pkg/common"
+)
+
+// now is the clock used for open intervals; tests replace it.
+var now = time.Now
+
+// Interval mirrors a single object produced by `timew export`.
+type Interval struct {
+	ID         int      `json:"id,omitempty"`
+	Start      string   `json:"start"`
+	End        string   `json:"end,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Annotation string   `json:"annotation,omitempty"`
+}
+
+// Open reports whether the interval is still being tracked.
+func (i Interval) Open() bool {
+	return i.End == ""
+}
+
+// Times returns the parsed bounds of the interval; open intervals end now.
+func (i Interval) Times() (time.Time, time.Time, error) {
+	start, err := common.ParseDate(i.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if i.Open() {
+		return start, now().UTC(), nil
+	}
+	end, err := common.ParseDate(i.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return start, end, nil
+}
+
+// Duration returns the tracked length of the interval.
+func (i Interval) Duration() time.Duration {
+	start, end, err := i.Times()
+	if err != nil {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// ParseIntervals decodes the output of `timew export`.
+func ParseIntervals(out string) ([]Interval, error) {
+	var intervals []Interval
+	if strings.TrimSpace(out) == "" {
+		return intervals, nil
+	}
+	if err := json.Unmarshal([]byte(out), &intervals); err != nil {
+		return nil, fmt.Errorf("could not parse timew export: %v", err)
+	}
+	return intervals, nil
+}
+
+// ExportIntervals runs `timew export` for the given range arguments.
+func ExportIntervals(ctx context.Context, rangeArgs ...string) ([]Interval, error) {
+	out, err := runTimew(ctx, append([]string{"export"}, rangeArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseIntervals(out)
+}
+
+// ExportBetween exports the intervals overlapping [from, to).
+func ExportBetween(ctx context.Context, from, to time.Time) ([]Interval, error) {
+	return ExportIntervals(ctx, common.CommandDate(from), "-", common.CommandDate(to))
+}
@@ -0,0 +1,318 @@
+package timewarrior
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"warmcp/pkg/common"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Rate bills time tagged Tag to Client at Rate per hour.
+type Rate struct {
+	Tag    string  `json:"tag"`
+	Client string  `json:"client"`
+	Rate   float64 `json:"rate"`
+}
+
+// RateTable holds the rates and the rounding rules applied to each invoice line.
+// Rounding is "up", "down" or "nearest"; Increment is in minutes, 0 meaning exact.
+type RateTable struct {
+	Currency  string `json:"currency,omitempty"`
+	Rounding  string `json:"rounding,omitempty"`
+	Increment int    `json:"increment_minutes,omitempty"`
+	Rates     []Rate `json:"rates"`
+}
+
+// CurrentRateTable returns the billing section of the warmcp config file.
+func CurrentRateTable() RateTable {
+	billing := common.CurrentConfig().Billing
+	table := RateTable{Currency: billing.Currency, Rounding: billing.Rounding, Increment: billing.Increment}
+	for _, r := range billing.Rates {
+		table.Rates = append(table.Rates, Rate{Tag: r.Tag, Client: r.Client, Rate: r.Rate})
+	}
+	return table
+}
+
+func (t RateTable) validate() error {
+	switch t.Rounding {
+	case "", "up", "down", "nearest":
+	default:
+		return fmt.Errorf("unknown rounding %q (use up, down or nearest)", t.Rounding)
+	}
+	if t.Increment < 0 {
+		return fmt.Errorf("increment must not be negative")
+	}
+	return nil
+}
+
+// rateFor returns the rate of the first interval tag that has one.
+func (t RateTable) rateFor(tags []string) (Rate, bool) {
+	for _, tag := range tags {
+		for _, r := range t.Rates {
+			if r.Tag == tag {
+				return r, true
+			}
+		}
+	}
+	return Rate{}, false
+}
+
+// round applies the rounding rule to a duration.
+func (t RateTable) round(d time.Duration) time.Duration {
+	inc := time.Duration(t.Increment) * time.Minute
+	if inc <= 0 {
+		return d
+	}
+	switch t.Rounding {
+	case "down":
+		return d.Truncate(inc)
+	case "nearest":
+		return d.Round(inc)
+	}
+	if r := d.Truncate(inc); r < d {
+		return r + inc
+	}
+	return d
+}
+
+// InvoiceLine is the time billed to one client on one day.
+type InvoiceLine struct {
+	Client      string  `json:"client"`
+	Date        string  `json:"date"`
+	Description string  `json:"description"`
+	Tracked     float64 `json:"tracked_hours"`
+	Hours       float64 `json:"hours"`
+	Rate        float64 `json:"rate"`
+	Amount      float64 `json:"amount"`
+}
+
+// ClientTotal sums the invoice lines of one client.
+type ClientTotal struct {
+	Client string  `json:"client"`
+	Hours  float64 `json:"hours"`
+	Amount float64 `json:"amount"`
+}
+
+// Timesheet is the billing view of a range of tracked time.
+type Timesheet struct {
+	Start        string        `json:"start"`
+	End          string        `json:"end"`
+	Currency     string        `json:"currency,omitempty"`
+	Lines        []InvoiceLine `json:"lines"`
+	Clients      []ClientTotal `json:"clients"`
+	Hours        float64       `json:"total_hours"`
+	Amount       float64       `json:"total_amount"`
+	Unbilled     float64       `json:"unbilled_hours"`
+	UnbilledTags []string      `json:"unbilled_tags,omitempty"`
+}
+
+func hours(d time.Duration) float64 {
+	return math.Round(d.Hours()*100) / 100
+}
+
+func money(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// BuildTimesheet groups the intervals clipped to [start, end) into one invoice
+// line per client and local day, splitting intervals that cross midnight.
+// Rounding applies to each line, not to each interval, so many short entries
+// are not each rounded up.
+func BuildTimesheet(intervals []Interval, table RateTable, start, end time.Time) Timesheet {
+	type lineKey struct{ client, date string }
+	type acc struct {
+		rate     float64
+		tracked  time.Duration
+		notes    []string
+		noteSeen map[string]bool
+	}
+	lines := map[lineKey]*acc{}
+	var unbilled time.Duration
+	unbilledTags := map[string]bool{}
+
+	for _, i := range intervals {
+		s, e, err := i.Times()
+		if err != nil {
+			continue
+		}
+		if s.Before(start) {
+			s = start
+		}
+		if e.After(end) {
+			e = end
+		}
+		if !e.After(s) {
+			continue
+		}
+		r, ok := table.rateFor(i.Tags)
+		if !ok {
+			unbilled += e.Sub(s)
+			for _, tag := range i.Tags {
+				unbilledTags[tag] = true
+			}
+			continue
+		}
+		note := i.Annotation
+		if note == "" {
+			note = strings.Join(i.Tags, " ")
+		}
+		for s.Before(e) {
+			day := common.StartOfDay(s, start.Location())
+			dayEnd := e
+			if next := day.AddDate(0, 0, 1); next.Before(e) {
+				dayEnd = next
+			}
+			key := lineKey{r.Client, day.Format(common.DayLayout)}
+			a, ok := lines[key]
+			if !ok {
+				a = &acc{rate: r.Rate, noteSeen: map[string]bool{}}
+				lines[key] = a
+			}
+			a.tracked += dayEnd.Sub(s)
+			if !a.noteSeen[note] {
+				a.noteSeen[note] = true
+				a.notes = append(a.notes, note)
+			}
+			s = dayEnd
+		}
+	}
+
+	sheet := Timesheet{
+		Start:    start.Format(common.DayLayout),
+		End:      end.AddDate(0, 0, -1).Format(common.DayLayout),
+		Currency: table.Currency,
+		Lines:    []InvoiceLine{},
+		Clients:  []ClientTotal{},
+		Unbilled: hours(unbilled),
+	}
+	keys := make([]lineKey, 0, len(lines))
+	for k := range lines {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].client != keys[j].client {
+			return keys[i].client < keys[j].client
+		}
+		return keys[i].date < keys[j].date
+	})
+	for _, k := range keys {
+		a := lines[k]
+		billed := hours(table.round(a.tracked))
+		line := InvoiceLine{
+			Client:      k.client,
+			Date:        k.date,
+			Description: strings.Join(a.notes, "; "),
+			Tracked:     hours(a.tracked),
+			Hours:       billed,
+			Rate:        a.rate,
+			Amount:      money(billed * a.rate),
+		}
+		sheet.Lines = append(sheet.Lines, line)
+		if n := len(sheet.Clients); n == 0 || sheet.Clients[n-1].Client != k.client {
+			sheet.Clients = append(sheet.Clients, ClientTotal{Client: k.client})
+		}
+		total := &sheet.Clients[len(sheet.Clients)-1]
+		total.Hours = money(total.Hours + line.Hours)
+		total.Amount = money(total.Amount + line.Amount)
+		sheet.Hours = money(sheet.Hours + line.Hours)
+		sheet.Amount = money(sheet.Amount + line.Amount)
+	}
+	for tag := range unbilledTags {
+		sheet.UnbilledTags = append(sheet.UnbilledTags, tag)
+	}
+	sort.Strings(sheet.UnbilledTags)
+	return sheet
+}
+
+// CSV renders the invoice lines, followed by a total row per client and a grand total.
+func (t Timesheet) CSV() (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	num := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	w.Write([]string{"client", "date", "description", "hours", "rate", "amount"})
+	for _, l := range t.Lines {
+		w.Write([]string{l.Client, l.Date, l.Description, num(l.Hours), num(l.Rate), num(l.Amount)})
+	}
+	for _, c := range t.Clients {
+		w.Write([]string{c.Client, "total", "", num(c.Hours), "", num(c.Amount)})
+	}
+	w.Write([]string{"", "total", t.Currency, num(t.Hours), "", num(t.Amount)})
+	w.Flush()
+	return buf.String(), w.Error()
+}
+
+// monthRange returns the first day of the current month in loc and the first day of the next.
+func monthRange(loc *time.Location) (time.Time, time.Time) {
+	n := now().In(loc)
+	start := time.Date(n.Year(), n.Month(), 1, 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 1, 0)
+}
+
+func timesheetHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	startArg, _ := argsMap["start"].(string)
+	endArg, _ := argsMap["end"].(string)
+	format, _ := argsMap["format"].(string)
+
+	loc := common.Location(ctx)
+	start, end := monthRange(loc)
+	if startArg != "" {
+		t, err := common.ParseDay(startArg, loc)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		start = t
+	}
+	if endArg != "" {
+		t, err := common.ParseDay(endArg, loc)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		end = t.AddDate(0, 0, 1)
+	}
+	if !end.After(start) {
+		return mcp.NewToolResultError("end must not be before start"), nil
+	}
+
+	table := CurrentRateTable()
+	if rounding, ok := argsMap["rounding"].(string); ok && rounding != "" {
+		table.Rounding = rounding
+	}
+	if inc, ok := argsMap["increment"].(float64); ok {
+		table.Increment = int(inc)
+	}
+	if err := table.validate(); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if len(table.Rates) == 0 {
+		return mcp.NewToolResultError("no rates configured: add billing.rates to the warmcp config file"), nil
+	}
+
+	intervals, err := ExportBetween(ctx, start, end)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	sheet := BuildTimesheet(intervals, table, start, end)
+
+	if format == "csv" {
+		out, err := sheet.CSV()
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText(out), nil
+	}
+	data, err := json.MarshalIndent(sheet, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
@@ -3,18 +3,69 @@ package timewarrior
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 	"warmcp/pkg/common"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 )
 
-func runTimew(args ...string) (string, error) {
-	env := []string{
-		fmt.Sprintf("TIMEW_CONFIG=%s", common.GetTimewConfigPath()),
+func runTimew(ctx context.Context, args ...string) (string, error) {
+	return common.RunCommand(ctx, common.CurrentConfig().Binaries.Timew, common.TimewEnv(ctx), nil, args...)
+}
+
+// runTimewIn runs timew with TZ set to loc, so that it reads ranges and
+// prints times in that zone.
+func runTimewIn(ctx context.Context, loc *time.Location, args ...string) (string, error) {
+	env := common.TimewEnv(ctx)
+	if loc != time.Local {
+		env = append(env, "TZ="+loc.String())
+	}
+	return common.RunCommand(ctx, common.CurrentConfig().Binaries.Timew, env, nil, args...)
+}
+
+// Start begins tracking a new interval with the given tags.
+func Start(ctx context.Context, tags ...string) (string, error) {
+	return runTimew(ctx, append([]string{"start"}, tags...)...)
+}
+
+// Stop ends the interval currently being tracked.
+func Stop(ctx context.Context) (string, error) {
+	return runTimew(ctx, "stop")
+}
+
+// Diagnostics returns the `timew diagnostics` report, which shows the
+// configuration file and database Timewarrior resolved.
+func Diagnostics(ctx context.Context) (string, error) {
+	return runTimew(ctx, "diagnostics")
+}
+
+// ActiveTags returns the tags of the interval currently being tracked, and
+// false if nothing is being tracked.
+func ActiveTags(ctx context.Context) ([]string, bool, error) {
+	out, err := runTimew(ctx, "get", "dom.active")
+	if err != nil || strings.TrimSpace(out) != "1" {
+		return nil, false, err
 	}
-	return common.RunCommand("timew", env, nil, args...)
+	out, err = runTimew(ctx, "get", "dom.active.tag.count")
+	if err != nil {
+		return nil, true, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(out))
+	if err != nil {
+		return nil, true, fmt.Errorf("unexpected tag count %q", out)
+	}
+	tags := make([]string, 0, count)
+	for i := 1; i <= count; i++ {
+		tag, err := runTimew(ctx, "get", fmt.Sprintf("dom.active.tag.%d", i))
+		if err != nil {
+			return nil, true, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, true, nil
 }
 
 func RegisterHandlers(s *server.MCPServer) {
@@ -32,16 +83,69 @@ func RegisterHandlers(s *server.MCPServer) {
 		mcp.WithDescription("Continue tracking the most recent activity. PROMPT FOR CONFIRMATION."),
 	), continueHandler)
 
+	s.AddTool(mcp.NewTool("timew_modify",
+		mcp.WithDescription("Move the start or end of a tracked interval. PROMPT FOR CONFIRMATION."),
+		mcp.WithNumber("id", mcp.Required(), mcp.Description("Interval ID, as in @1 for the most recent")),
+		mcp.WithString("boundary", mcp.Required(), mcp.Enum("start", "end"), mcp.Description("Which end of the interval to move")),
+		mcp.WithString("datetime", mcp.Required(), mcp.Description("New time, e.g. 09:30, 2024-01-15T09:30 or yesterday")),
+	), modifyHandler)
+	common.RequireVersion("timew_modify", "timew", "1.1.0")
+
 	s.AddTool(mcp.NewTool("timew_summary",
 		mcp.WithDescription("Get time tracking summary. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		common.WithTimezone(),
 		mcp.WithString("range", mcp.Description("Time range like ':week', ':day'")),
 	), summaryHandler)
 
 	s.AddTool(mcp.NewTool("timew_export",
-		mcp.WithDescription("Export time data as JSON. NO CONFIRMATION NEEDED."),
+		mcp.WithDescription("Export time data as JSON, with start and end in RFC 3339 in the requested timezone. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		common.WithTimezone(),
 		mcp.WithString("range", mcp.Description("Time range like ':week', ':day'")),
 	), exportHandler)
 
+	s.AddTool(mcp.NewTool("timew_export_ical",
+		mcp.WithDescription("Export tracked intervals as an RFC 5545 calendar of VEVENTs. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithString("range", mcp.Description("Time range like ':week', ':day'. Default: "+defaultCalendarRange)),
+	), exportICalHandler)
+
+	s.AddResource(mcp.Resource{
+		URI:         "timew://calendar.ics",
+		Name:        "Timewarrior Calendar",
+		Description: "This week's tracked intervals as iCalendar VEVENTs",
+		MIMEType:    "text/calendar",
+	}, calendarResourceHandler)
+
+	s.AddResourceTemplate(mcp.NewResourceTemplate(
+		"timew://calendar.ics{?range}",
+		"Timewarrior Calendar (range)",
+		mcp.WithTemplateDescription("Tracked intervals in a range as iCalendar VEVENTs"),
+		mcp.WithTemplateMIMEType("text/calendar"),
+	), calendarResourceHandler)
+
+	s.AddTool(mcp.NewTool("timew_timesheet",
+		mcp.WithDescription("Billing timesheet: per-client, per-day invoice lines with hours, rates, amounts and totals, from the billing section of the warmcp config file. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		common.WithTimezone(),
+		mcp.WithString("start", mcp.Description("First day (YYYY-MM-DD). Default: first day of this month")),
+		mcp.WithString("end", mcp.Description("Last day, inclusive (YYYY-MM-DD). Default: last day of this month")),
+		mcp.WithString("rounding", mcp.Enum("up", "down", "nearest"), mcp.Description("Override how each line is rounded to the increment")),
+		mcp.WithNumber("increment", mcp.Description("Override the billing increment in minutes (0 bills exact time)")),
+		mcp.WithString("format", mcp.Enum("json", "csv"), mcp.Description("Output format. Default: json")),
+	), timesheetHandler)
+
+	s.AddTool(mcp.NewTool("timew_gaps",
+		mcp.WithDescription("Check tracked time against working hours (timewarrior exclusions): untracked gaps, overlapping intervals, long-running open intervals and untagged entries, with suggested timew commands to fix each. NO CONFIRMATION NEEDED."),
+		mcp.WithReadOnlyHintAnnotation(true),
+		common.WithTimezone(),
+		mcp.WithString("start", mcp.Description("First day (YYYY-MM-DD). Default: six days ago")),
+		mcp.WithString("end", mcp.Description("Last day, inclusive (YYYY-MM-DD). Default: today")),
+		mcp.WithNumber("min_gap", mcp.Description("Ignore gaps shorter than this many minutes. Default: 15")),
+		mcp.WithNumber("max_open", mcp.Description("Flag open intervals running longer than this many hours. Default: 8")),
+	), gapsHandler)
+
 	s.AddTool(mcp.NewTool("timew_raw",
 		mcp.WithDescription("Run raw timew command. PROMPT FOR CONFIRMATION."),
 		mcp.WithString("command", mcp.Required(), mcp.Description("Full timew command arguments")),
@@ -52,7 +156,7 @@ func startHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRe
 	argsMap, _ := req.Params.Arguments.(map[string]any)
 	tags, _ := argsMap["tags"].(string)
 	args := append([]string{"start"}, strings.Fields(tags)...)
-	out, err := runTimew(args...)
+	out, err := runTimew(ctx, args...)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -63,7 +167,7 @@ func stopHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRes
 	argsMap, _ := req.Params.Arguments.(map[string]any)
 	tags, _ := argsMap["tags"].(string)
 	args := append([]string{"stop"}, strings.Fields(tags)...)
-	out, err := runTimew(args...)
+	out, err := runTimew(ctx, args...)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -71,7 +175,28 @@ func stopHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolRes
 }
 
 func continueHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	out, err := runTimew("continue")
+	out, err := runTimew(ctx, "continue")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(out), nil
+}
+
+func modifyHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	argsMap, _ := req.Params.Arguments.(map[string]any)
+	id, _ := argsMap["id"].(float64)
+	boundary, _ := argsMap["boundary"].(string)
+	datetime, _ := argsMap["datetime"].(string)
+	if id < 1 || id != float64(int(id)) {
+		return mcp.NewToolResultError("id must be a positive interval ID"), nil
+	}
+	if boundary != "start" && boundary != "end" {
+		return mcp.NewToolResultError("boundary must be start or end"), nil
+	}
+	if datetime == "" {
+		return mcp.NewToolResultError("datetime is required"), nil
+	}
+	out, err := runTimew(ctx, "modify", boundary, fmt.Sprintf("@%d", int(id)), datetime)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -85,7 +210,7 @@ func summaryHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallTool
 	if trange != "" {
 		args = append(args, trange)
 	}
-	out, err := runTimew(args...)
+	out, err := runTimewIn(ctx, common.Location(ctx), args...)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
@@ -99,17 +224,24 @@ func exportHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolR
 	if trange != "" {
 		args = append(args, trange)
 	}
-	out, err := runTimew(args...)
+	out, err := runTimewIn(ctx, common.Location(ctx), args...)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
-	return mcp.NewToolResultText(out), nil
+	if strings.TrimSpace(out) == "" {
+		return mcp.NewToolResultText("[]"), nil
+	}
+	data, err := common.LocalizeJSON([]byte(out), common.Location(ctx))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("could not parse timew export: %v", err)), nil
+	}
+	return mcp.NewToolResultText(string(data)), nil
 }
 
 func rawHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	argsMap, _ := req.Params.Arguments.(map[string]any)
 	cmd, _ := argsMap["command"].(string)
-	out, err := runTimew(strings.Fields(cmd)...)
+	out, err := runTimew(ctx, strings.Fields(cmd)...)
 	if err != nil {
 		return mcp.NewToolResultError(err.Error()), nil
 	}
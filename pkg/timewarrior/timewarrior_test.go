@@ -2,10 +2,15 @@ package timewarrior
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 	"warmcp/pkg/common"
 
 	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -17,7 +22,7 @@ type MockRunner struct {
 	Err      error
 }
 
-func (m *MockRunner) Run(name string, env []string, baseArgs []string, args ...string) (string, error) {
+func (m *MockRunner) Run(ctx context.Context, name string, env []string, baseArgs []string, args ...string) (string, error) {
 	m.LastCmd = name
 	m.LastEnv = env
 	m.LastArgs = append(baseArgs, args...)
@@ -41,6 +46,53 @@ func TestTimewStart(t *testing.T) {
 	assert.Contains(t, mock.LastArgs, "Work")
 }
 
+func TestTimewModify(t *testing.T) {
+	mock := &MockRunner{Output: ""}
+	common.Runner = mock
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"id": float64(2), "boundary": "end", "datetime": "17:30"}
+	res, err := modifyHandler(context.Background(), req)
+	assert.NoError(t, err)
+	assert.False(t, res.IsError)
+	assert.Equal(t, []string{"modify", "end", "@2", "17:30"}, mock.LastArgs)
+
+	req.Params.Arguments = map[string]any{"id": float64(2), "boundary": "middle", "datetime": "17:30"}
+	res, _ = modifyHandler(context.Background(), req)
+	assert.True(t, res.IsError)
+}
+
+func TestVersionGating(t *testing.T) {
+	v, err := common.ParseVersion("timewarrior 1.0.0\n")
+	assert.NoError(t, err)
+	assert.False(t, v.AtLeast(common.Version{Major: 1, Minor: 1}))
+
+	common.Runner = &MockRunner{Output: "1.0.0"}
+	s := server.NewMCPServer("test", "1.0.0")
+	RegisterHandlers(s)
+	common.ProbeCapabilities()
+	common.GateTools(s)
+	assert.Nil(t, s.GetTool("timew_modify"))
+	caps := common.CurrentCapabilities()
+	assert.Equal(t, "1.0.0", caps.Timew.Version)
+	assert.Equal(t, []common.DisabledTool{{Name: "timew_modify", Reason: "needs timew 1.1.0 or later, found 1.0.0"}}, caps.DisabledTools)
+
+	// An upgrade picked up on reload brings the tool back.
+	common.Runner = &MockRunner{Output: "1.4.3"}
+	common.ProbeCapabilities()
+	common.GateTools(s)
+	assert.NotNil(t, s.GetTool("timew_modify"))
+	assert.Empty(t, common.CurrentCapabilities().DisabledTools)
+}
+
+func TestReadOnlyAnnotations(t *testing.T) {
+	s := server.NewMCPServer("test", "1.0.0")
+	RegisterHandlers(s)
+	for name, tool := range s.ListTools() {
+		assert.Equal(t, strings.Contains(tool.Tool.Description, "NO CONFIRMATION NEEDED"), *tool.Tool.Annotations.ReadOnlyHint, name)
+	}
+}
+
 func TestTimewSummary(t *testing.T) {
 	mock := &MockRunner{Output: "Summary data"}
 	common.Runner = mock
@@ -57,3 +109,202 @@ func TestTimewSummary(t *testing.T) {
 	assert.Contains(t, mock.LastArgs, "summary")
 	assert.Contains(t, mock.LastArgs, ":day")
 }
+
+func TestTimewExportTimezone(t *testing.T) {
+	mock := &MockRunner{Output: `[{"id":1,"start":"20240105T230000Z","end":"20240105T233000Z","tags":["dev"]}]`}
+	common.Runner = mock
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+	ctx := common.WithLocation(context.Background(), tokyo)
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"range": ":week"}
+	res, err := exportHandler(ctx, req)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":1,"start":"2024-01-06T08:00:00+09:00","end":"2024-01-06T08:30:00+09:00","tags":["dev"]}]`,
+		res.Content[0].(mcp.TextContent).Text)
+	assert.Contains(t, mock.LastEnv, "TZ=Asia/Tokyo")
+
+	summaryHandler(ctx, req)
+	assert.Contains(t, mock.LastEnv, "TZ=Asia/Tokyo")
+}
+
+func TestTimewExportICal(t *testing.T) {
+	mock := &MockRunner{Output: `[{"id":1,"start":"20240101T090000Z","end":"20240101T103000Z","tags":["ClientA","dev"],"annotation":"sprint"}]`}
+	common.Runner = mock
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"range": ":day"}
+
+	res, err := exportICalHandler(context.Background(), req)
+	assert.NoError(t, err)
+	ics := res.Content[0].(mcp.TextContent).Text
+	assert.Contains(t, mock.LastArgs, "export")
+	assert.Contains(t, mock.LastArgs, ":day")
+	assert.Contains(t, ics, "BEGIN:VEVENT\r\n")
+	assert.Contains(t, ics, "DTSTART:20240101T090000Z\r\n")
+	assert.Contains(t, ics, "DTEND:20240101T103000Z\r\n")
+	assert.Contains(t, ics, "SUMMARY:ClientA dev\r\n")
+	assert.Contains(t, ics, "CATEGORIES:ClientA,dev\r\n")
+}
+
+func TestParseConfig(t *testing.T) {
+	cfg := ParseConfig(`# billing
+import /usr/share/timewarrior/themes/dark.theme
+warmcp.billing.rate.acme = 120
+warmcp:
+  billing:
+    client.acme = Acme Corp  # invoiced monthly
+    increment = 15
+exclusions.monday = <9:00 >18:00
+`)
+	assert.Equal(t, "120", cfg["warmcp.billing.rate.acme"])
+	assert.Equal(t, "Acme Corp", cfg["warmcp.billing.client.acme"])
+	assert.Equal(t, "15", cfg["warmcp.billing.increment"])
+	assert.Equal(t, "<9:00 >18:00", cfg["exclusions.monday"])
+	assert.Len(t, cfg, 4)
+}
+
+func TestBuildTimesheet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`billing:
+  rounding: up
+  increment: 15
+  rates:
+    - {tag: acme, client: Acme Corp, rate: 100}
+    - {tag: globex, rate: 80}
+`), 0o600))
+	cfg, err := common.LoadConfig(path)
+	assert.NoError(t, err)
+	assert.NoError(t, common.SetConfig(cfg))
+	defer common.SetConfig(common.DefaultConfig())
+	table := CurrentRateTable()
+	assert.Equal(t, "globex", table.Rates[1].Client)
+
+	intervals := []Interval{
+		// Starts before the range; only the part inside is billed.
+		{Start: "20231231T230000Z", End: "20240101T001000Z", Tags: []string{"acme"}},
+		{Start: "20240101T090000Z", End: "20240101T092000Z", Tags: []string{"dev", "acme"}, Annotation: "API"},
+		{Start: "20240101T100000Z", End: "20240101T110000Z", Tags: []string{"globex"}},
+		{Start: "20240102T100000Z", End: "20240102T103000Z", Tags: []string{"admin"}},
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sheet := BuildTimesheet(intervals, table, start, start.AddDate(0, 0, 7))
+
+	assert.Equal(t, "2024-01-07", sheet.End)
+	assert.Len(t, sheet.Lines, 2)
+	acme := sheet.Lines[0]
+	assert.Equal(t, "Acme Corp", acme.Client)
+	assert.Equal(t, "acme; API", acme.Description)
+	assert.Equal(t, 0.5, acme.Tracked)
+	assert.Equal(t, 0.5, acme.Hours)
+	assert.Equal(t, 50.0, acme.Amount)
+	assert.Equal(t, 80.0, sheet.Lines[1].Amount)
+	assert.Equal(t, 130.0, sheet.Amount)
+	assert.Equal(t, 0.5, sheet.Unbilled)
+	assert.Equal(t, []string{"admin"}, sheet.UnbilledTags)
+
+	table.Increment = 60
+	sheet = BuildTimesheet(intervals, table, start, start.AddDate(0, 0, 7))
+	assert.Equal(t, 1.0, sheet.Lines[0].Hours)
+
+	// An interval crossing midnight is billed to both days.
+	table.Increment = 0
+	sheet = BuildTimesheet([]Interval{{Start: "20240102T233000Z", End: "20240103T010000Z", Tags: []string{"acme"}}}, table, start, start.AddDate(0, 0, 7))
+	assert.Len(t, sheet.Lines, 2)
+	assert.Equal(t, "2024-01-02", sheet.Lines[0].Date)
+	assert.Equal(t, 0.5, sheet.Lines[0].Hours)
+	assert.Equal(t, "2024-01-03", sheet.Lines[1].Date)
+	assert.Equal(t, 1.0, sheet.Lines[1].Hours)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`billing:
+  rounding: sideways
+  rates: [{tag: acme, rate: 100}, {tag: acme, rate: 90}, {rate: 10}]
+`), 0o600))
+	_, err = common.LoadConfig(path)
+	assert.ErrorContains(t, err, `billing.rounding "sideways" must be up, down or nearest`)
+	assert.ErrorContains(t, err, "tag acme has more than one rate")
+	assert.ErrorContains(t, err, "billing.rates[2] has no tag")
+}
+
+func TestTimewTimesheetCSV(t *testing.T) {
+	mock := &MockRunner{Output: `[{"start":"20240110T120000Z","end":"20240110T134500Z","tags":["acme"]}]`}
+	common.Runner = mock
+
+	cfg := common.DefaultConfig()
+	cfg.Billing = common.Billing{Currency: "EUR", Rates: []common.BillingRate{{Tag: "acme", Client: "acme", Rate: 90}}}
+	assert.NoError(t, common.SetConfig(cfg))
+	defer common.SetConfig(common.DefaultConfig())
+
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"start": "2024-01-01", "end": "2024-01-31", "format": "csv"}
+	res, err := timesheetHandler(context.Background(), req)
+	assert.NoError(t, err)
+	out := res.Content[0].(mcp.TextContent).Text
+	assert.Contains(t, mock.LastArgs, "export")
+	assert.Contains(t, mock.LastArgs, "2024-01-01T00:00:00")
+	assert.Contains(t, mock.LastArgs, "2024-02-01T00:00:00")
+	assert.Contains(t, out, "client,date,description,hours,rate,amount\n")
+	assert.Contains(t, out, "acme,2024-01-10,acme,1.75,90.00,157.50\n")
+	assert.Contains(t, out, ",total,EUR,1.75,,157.50\n")
+}
+
+func TestParseExclusions(t *testing.T) {
+	ex, err := ParseExclusions(ParseConfig(`define exclusions:
+  monday = <8:00 12:00-12:45 >17:30
+  days:
+    2024_01_02 = off
+holidays.en-US.2024_01_01 = New Year's Day
+`))
+	assert.NoError(t, err)
+	assert.False(t, ex.Default)
+
+	monday := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	periods := ex.WorkingPeriods(monday)
+	assert.Len(t, periods, 2)
+	assert.Equal(t, monday.Add(8*time.Hour), periods[0].start)
+	assert.Equal(t, monday.Add(12*time.Hour), periods[0].end)
+	assert.Equal(t, monday.Add(12*time.Hour+45*time.Minute), periods[1].start)
+	assert.Empty(t, ex.WorkingPeriods(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Empty(t, ex.WorkingPeriods(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)))
+
+	_, err = ParseExclusions(map[string]string{"exclusions.monday": "<8"})
+	assert.Error(t, err)
+
+	ex, err = ParseExclusions(map[string]string{})
+	assert.NoError(t, err)
+	assert.True(t, ex.Default)
+	assert.Empty(t, ex.WorkingPeriods(time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestFindGaps(t *testing.T) {
+	local := time.Local
+	time.Local = time.UTC
+	now = func() time.Time { return time.Date(2024, 1, 9, 12, 0, 0, 0, time.UTC) }
+	defer func() { time.Local, now = local, time.Now }()
+
+	ex, _ := ParseExclusions(map[string]string{})
+	intervals := []Interval{
+		{ID: 4, Start: "20240108T090000Z", End: "20240108T113000Z", Tags: []string{"acme", "big project"}},
+		{ID: 3, Start: "20240108T110000Z", End: "20240108T120000Z"},
+		{ID: 2, Start: "20240108T130000Z", End: "20240108T170000Z", Tags: []string{"acme"}},
+		{ID: 1, Start: "20240108T230000Z", Tags: []string{"acme"}},
+	}
+	day := time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)
+	report := FindGaps(intervals, ex, day, day.AddDate(0, 0, 1), 15*time.Minute, 8*time.Hour)
+
+	// Monday 12:00-13:00 is a gap; Tuesday is covered by the open interval until now.
+	assert.Len(t, report.Gaps, 1)
+	assert.Equal(t, 60, report.Gaps[0].Minutes)
+	assert.Equal(t, "timew track 2024-01-08T12:00:00 - 2024-01-08T13:00:00 acme \"big project\"", report.Gaps[0].Suggestion)
+	assert.Equal(t, 8*60+3*60, report.WorkingMinutes)
+	assert.Equal(t, 7*60+3*60, report.TrackedMinutes)
+
+	assert.Equal(t, []Overlap{{First: 4, Second: 3, Start: "2024-01-08T11:00:00", End: "2024-01-08T11:30:00", Minutes: 30}}, report.Overlaps)
+	assert.Len(t, report.LongOpen, 1)
+	assert.Equal(t, 1, report.LongOpen[0].ID)
+	assert.Equal(t, 13.0, report.LongOpen[0].Hours)
+	assert.Len(t, report.Untagged, 1)
+	assert.Equal(t, "timew tag @3 <tags>", report.Untagged[0].Suggestion)
+	assert.Len(t, report.Suggestions, 3)
+}